@@ -18,6 +18,7 @@ package v1alpha1
 import (
 	cmmeta "github.com/jetstack/cert-manager/pkg/apis/meta/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -38,10 +39,14 @@ type Component string
 
 // all Component used in harbor cluster full stack.
 const (
-	ComponentHarbor   Component = "harbor"
-	ComponentCache    Component = "cache"
-	ComponentStorage  Component = "storage"
-	ComponentDatabase Component = "database"
+	ComponentHarbor             Component = "harbor"
+	ComponentCache              Component = "cache"
+	ComponentStorage            Component = "storage"
+	ComponentDatabase           Component = "database"
+	ComponentDatabaseBackup     Component = "databaseBackup"
+	ComponentDatabaseRestore    Component = "databaseRestore"
+	ComponentDatabaseCredential Component = "databaseCredential"
+	ComponentStorageUsage       Component = "storageUsage"
 )
 
 const (
@@ -49,6 +54,24 @@ const (
 	InClusterComponent string = "inCluster"
 )
 
+// DatabaseCredentialRotationAnnotation triggers a rotation of the database passwords of harbor components,
+// a new value starts a new rotation.
+const DatabaseCredentialRotationAnnotation = "goharbor.io/database-credential-rotation"
+
+// DatabaseCredentialRotationComponentsAnnotation limits the rotation to the comma separated components,
+// e.g. "core,clair". All the components are rotated if it is not set.
+const DatabaseCredentialRotationComponentsAnnotation = "goharbor.io/database-credential-rotation-components"
+
+// DatabaseRestoreAnnotation triggers a restore of harbor databases on an existing HarborCluster,
+// a new value starts a new restore.
+const DatabaseRestoreAnnotation = "goharbor.io/database-restore"
+
+const (
+	RedisSentinelSchema string = "sentinel"
+	RedisServerSchema   string = "redis"
+	RedisClusterSchema  string = "cluster"
+)
+
 // HarborClusterSpec defines the desired state of HarborCluster
 type HarborClusterSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -134,9 +157,10 @@ type HarborClusterSpec struct {
 }
 
 type Storage struct {
-	// set the kind of which storage service to be used. Set the kind as "azure", "gcs", "s3", "oss", "swift" or "inCluster", and fill the information.
+	// set the kind of which storage service to be used. Set the kind as "azure", "gcs", "s3", "oss", "swift", "filesystem" or "inCluster", and fill the information.
 	// in the options section. inCluster indicates the local storage service of harbor-cluster. We use minIO as a default built-in object storage service.
-	// +kubebuilder:validation:Enum=inCluster;azure;gcs;s3;oss;swift
+	// filesystem stores the objects in a persistent volume claim mounted by registry and chart museum.
+	// +kubebuilder:validation:Enum=inCluster;azure;gcs;s3;oss;swift;filesystem
 	Kind string `json:"kind"`
 
 	// inCLuster options.
@@ -156,6 +180,23 @@ type Storage struct {
 
 	// Oss options.
 	Oss *Oss `json:"oss,omitempty"`
+
+	// FileSystem options.
+	FileSystem *FileSystem `json:"filesystem,omitempty"`
+}
+
+type FileSystem struct {
+	// The size of the persistent volume claim, it can be expanded if the storage class allows.
+	// +kubebuilder:validation:Required
+	Size resource.Quantity `json:"size"`
+	// The storage class of the persistent volume claim, the default storage class is used if it is not set.
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+	// The access mode of the persistent volume claim, default is ReadWriteMany.
+	// ReadWriteOnce requires a single replica of harbor, the pods using the claim are scheduled to the same node.
+	// +kubebuilder:validation:Enum=ReadWriteMany;ReadWriteOnce
+	// +optional
+	AccessMode corev1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
 }
 
 type Oss struct {
@@ -208,10 +249,15 @@ type S3 struct {
 	Region string `json:"region"`
 	// +kubebuilder:validation:Required
 	Bucket string `json:"bucket"`
-	// +kubebuilder:validation:Required
+	// The static keys are not required if RoleArn is set.
+	// +optional
 	AccessKey string `json:"accesskey"`
-	// +kubebuilder:validation:Required
+	// +optional
 	SecretKey string `json:"secretkey"`
+	// The IAM role assumed by registry and chart museum through IAM roles for service accounts (IRSA) on EKS,
+	// the service account of them is annotated with the role instead of using the static keys.
+	// +optional
+	RoleArn string `json:"rolearn,omitempty"`
 	// +kubebuilder:validation:Required
 	RegionEndpoint string `json:"regionendpoint"`
 	Encrypt        bool   `json:"encrypt,omitempty"`
@@ -228,6 +274,10 @@ type Gcs struct {
 	Bucket string `json:"bucket"`
 	// The base64 encoded json file which contains the key
 	EncodedKey string `json:"encodedkey"`
+	// The google service account impersonated by registry and chart museum through Workload Identity on GKE,
+	// the service account of them is annotated with it instead of using the encoded key.
+	// +optional
+	ServiceAccount string `json:"serviceaccount,omitempty"`
 	// +kubebuilder:validation:Required
 	RootDirectory string `json:"rootdirectory"`
 	ChunkSize     string `json:"chunksize,omitempty"`
@@ -266,6 +316,72 @@ type MinIOSpec struct {
 	// If provided, use these requests and limit for cpu/memory resource allocation
 	// +optional
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// The bucket of registry, default is "harbor".
+	// +kubebuilder:validation:Pattern=`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`
+	// +optional
+	Bucket string `json:"bucket,omitempty"`
+	// The bucket of chart museum, default is the bucket of registry.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`
+	// +optional
+	ChartMuseumBucket string `json:"chartMuseumBucket,omitempty"`
+	// The region of buckets, default is "us-east-1".
+	// +optional
+	Region string `json:"region,omitempty"`
+	// The root directory of registry in the bucket. The charts are stored in "<rootDirectory>/chartmuseum"
+	// if chart museum shares the bucket of registry, otherwise in "<rootDirectory>" of the chart museum bucket.
+	// +optional
+	RootDirectory string `json:"rootDirectory,omitempty"`
+	// The properties of the buckets, they are reconciled on every pass.
+	// +optional
+	BucketPolicy *MinIOBucketPolicy `json:"bucketPolicy,omitempty"`
+	// Serve minIO in HTTPS with the certificate issued from CertificateIssuerRef, it can only be set on creation.
+	// +optional
+	EnableTLS bool `json:"enableTLS,omitempty"`
+	// The zones appended to expand the capacity of minIO. The first zone is defined by replicas, volumesPerServer
+	// and volumeClaimTemplate. The existing zones can not be modified or removed.
+	// +optional
+	Zones []MinIOZone `json:"zones,omitempty"`
+	// The percentage of the provisioned volume capacity, above which the StorageUsageWarning condition is set,
+	// default is 80.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	UsageWarningPercentage int `json:"usageWarningPercentage,omitempty"`
+}
+
+type MinIOZone struct {
+	// The name of zone, it is unique in the zones.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// Number of servers in the zone.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Required
+	Servers int32 `json:"servers"`
+	// Number of persistent volumes that will be attached per server.
+	// The servers multiplied by the volumes must be at least 4.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Required
+	VolumesPerServer int32 `json:"volumesPerServer"`
+	// VolumeClaimTemplate of the zone, default is the one of the first zone.
+	// +optional
+	VolumeClaimTemplate *corev1.PersistentVolumeClaim `json:"volumeClaimTemplate,omitempty"`
+	// Resources of the servers in the zone, default is the one of the first zone.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+type MinIOBucketPolicy struct {
+	// Enable versioning of the buckets, the versioning is suspended if it is disabled after enabled.
+	// +optional
+	Versioning bool `json:"versioning,omitempty"`
+	// The hard quota of each bucket, e.g. "500Gi". There is no quota if it is not set.
+	// +optional
+	Quota *resource.Quantity `json:"quota,omitempty"`
+	// Create the buckets with object locking, it requires versioning and can only be set on creation.
+	// +optional
+	ObjectLocking bool `json:"objectLocking,omitempty"`
 }
 
 type PostgresSQL struct {
@@ -282,9 +398,169 @@ type PostgresSQL struct {
 	// username: root
 	// password: password
 	// database: database
-	SecretName     string `json:"secretName,omitempty"`
-	SslConfig      string `json:"sslConfig,omitempty"`
-	ConnectTimeout int    `json:"connectTimeout,omitempty"`
+	SecretName string `json:"secretName,omitempty"`
+	// The ssl mode of the database connections, default is disable.
+	// It is passed to the harbor components which read it through harbor operator, i.e. clair and notary.
+	// +kubebuilder:validation:Enum=disable;require;verify-ca;verify-full
+	// +optional
+	SslMode string `json:"sslMode,omitempty"`
+	// The secret contains the CA bundle "ca.crt" to verify the database server,
+	// and the client certificate "tls.crt","tls.key" if the database requires client certificate.
+	// The certificates are used by the operator and the backup and restore Jobs, not by the harbor components.
+	// +optional
+	SslConfig string `json:"sslConfig,omitempty"`
+	// Maximum wait for connection, in seconds. Zero means wait indefinitely.
+	// +optional
+	ConnectTimeout int `json:"connectTimeout,omitempty"`
+
+	// Scheduled logical backups of harbor databases.
+	// +optional
+	Backup *DatabaseBackup `json:"backup,omitempty"`
+
+	// Restore harbor databases from backups before harbor components start.
+	// +optional
+	Restore *DatabaseRestore `json:"restore,omitempty"`
+
+	// Continuous WAL archiving of inCluster database to the storage of harbor cluster.
+	// +optional
+	WalArchive *DatabaseWalArchive `json:"walArchive,omitempty"`
+
+	// Bootstrap the inCluster database as a clone of another database at a point in time.
+	// +optional
+	Clone *DatabaseClone `json:"clone,omitempty"`
+
+	// PostgreSQL parameters of inCluster database, e.g. max_connections and shared_buffers.
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// The pg_hba entries of inCluster database, they replace the default entries.
+	// +optional
+	PgHba []string `json:"pgHba,omitempty"`
+
+	// Patroni settings of inCluster database.
+	// +optional
+	Patroni *DatabasePatroni `json:"patroni,omitempty"`
+
+	// The connection pooler (PgBouncer) of inCluster database,
+	// harbor components connect to the pooler if it is enabled.
+	// +optional
+	ConnectionPooler *DatabaseConnectionPooler `json:"connectionPooler,omitempty"`
+
+	// Scheduled rotation of the database passwords of harbor components.
+	// +optional
+	CredentialRotation *DatabaseCredentialRotation `json:"credentialRotation,omitempty"`
+}
+
+type DatabaseCredentialRotation struct {
+	// The interval between rotations of each component, e.g. "720h".
+	// +kubebuilder:validation:Required
+	Interval metav1.Duration `json:"interval"`
+}
+
+type DatabasePatroni struct {
+	// The TTL of leader lock in seconds, it must not be less than loopWait + 2 * retryTimeout.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TTL uint32 `json:"ttl,omitempty"`
+	// Seconds the loop of Patroni sleeps.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	LoopWait uint32 `json:"loopWait,omitempty"`
+	// Timeout in seconds of DCS and PostgreSQL operations retries.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RetryTimeout uint32 `json:"retryTimeout,omitempty"`
+	// The maximum bytes a replica can lag to be able to participate in leader election.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaximumLagOnFailover *int64 `json:"maximumLagOnFailover,omitempty"`
+}
+
+type DatabaseConnectionPooler struct {
+	// +kubebuilder:validation:Required
+	Enabled bool `json:"enabled"`
+	// Number of pooler instances, default is 2.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// The pool mode, default is session. Harbor components rely on the session state,
+	// transaction mode breaks the prepared statements and the advisory locks of them.
+	// +kubebuilder:validation:Enum=session;transaction
+	// +optional
+	Mode string `json:"mode,omitempty"`
+	// Maximum connections from the pooler to each database.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxDBConnections *int32 `json:"maxDBConnections,omitempty"`
+	// The image of pooler, default is the image configured in postgres operator.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+type DatabaseWalArchive struct {
+	// +kubebuilder:validation:Required
+	Enabled bool `json:"enabled"`
+	// Cron schedule of the base backups, default is "30 00 * * *".
+	// +optional
+	BackupSchedule string `json:"backupSchedule,omitempty"`
+	// Number of base backups kept, default is 5.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BackupRetention int `json:"backupRetention,omitempty"`
+}
+
+type DatabaseClone struct {
+	// The name of HarborCluster whose database is cloned,
+	// its WAL must be archived to the same storage of this HarborCluster.
+	// +kubebuilder:validation:Required
+	ClusterName string `json:"clusterName"`
+	// The namespace of HarborCluster whose database is cloned, default is the namespace of this HarborCluster.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// The point in time to recover to, with time zone, e.g. "2020-09-01T12:00:00+00:00".
+	// +kubebuilder:validation:Required
+	Timestamp string `json:"timestamp"`
+}
+
+type DatabaseBackup struct {
+	// Cron schedule of the backups, e.g. "0 2 * * *".
+	// +kubebuilder:validation:Required
+	Schedule string `json:"schedule"`
+	// Number of backups kept for each database, default is 7.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Retention int `json:"retention,omitempty"`
+	// The image contains pg_dump, default is the postgres image of database version.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// +kubebuilder:validation:Required
+	Target DatabaseBackupTarget `json:"target"`
+}
+
+type DatabaseBackupTarget struct {
+	// Set the kind of backup target, "storage" stores the backups in the S3 compatible storage
+	// service of harbor cluster (inCluster or s3), "pvc" stores the backups in a persistent volume claim.
+	// +kubebuilder:validation:Enum=storage;pvc
+	Kind string `json:"kind"`
+	// The directory of backups in the storage bucket or the volume, default is "database-backup".
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+	// The name of persistent volume claim, required by pvc kind.
+	// +optional
+	ClaimName string `json:"claimName,omitempty"`
+}
+
+type DatabaseRestore struct {
+	// The backups to restore keyed by database name, the values are the locations
+	// reported in status.databaseBackups, e.g. "s3://bucket/database-backup/core/harbor-backup-core-1600000000.dump".
+	// The databases not listed are restored from their last successful backups.
+	// +optional
+	Backups map[string]string `json:"backups,omitempty"`
+	// The image contains pg_restore, default is the postgres image of database version.
+	// +optional
+	Image string `json:"image,omitempty"`
 }
 
 type Database struct {
@@ -312,16 +588,78 @@ type RedisSpec struct {
 	// External params following.
 	// The secret must contains "password".
 	SecretName string `json:"secretName,omitempty"`
+	// Connection pool settings following, they apply to the operator's
+	// health checks only, except that the pool size is passed to harbor core.
+	// Other harbor components use their own built-in pool settings.
 	// Maximum number of socket connections.
 	// Default is 10 connections per every CPU as reported by runtime.NumCPU.
 	PoolSize int `json:"poolSize,omitempty"`
+	// Dial timeout for establishing new connections.
+	// Default is 10 seconds.
+	// +optional
+	DialTimeout *metav1.Duration `json:"dialTimeout,omitempty"`
+	// Timeout for socket reads.
+	// Default is 30 seconds.
+	// +optional
+	ReadTimeout *metav1.Duration `json:"readTimeout,omitempty"`
+	// Timeout for socket writes.
+	// Default is 30 seconds.
+	// +optional
+	WriteTimeout *metav1.Duration `json:"writeTimeout,omitempty"`
+	// Amount of time client waits for connection if all connections are busy.
+	// Default is 30 seconds.
+	// +optional
+	PoolTimeout *metav1.Duration `json:"poolTimeout,omitempty"`
+	// Amount of time after which client closes idle connections.
+	// Default is 5 minutes.
+	// +optional
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+	// Frequency of idle checks made by idle connections reaper.
+	// Default is 1 minute.
+	// +optional
+	IdleCheckFrequency *metav1.Duration `json:"idleCheckFrequency,omitempty"`
 	// TLS Config to use. When set TLS will be negotiated.
 	// set the secret which type of Opaque, and contains "tls.key","tls.crt","ca.crt".
 	TlsConfig string `json:"tlsConfig,omitempty"`
 	GroupName string `json:"groupName,omitempty"`
-	// +kubebuilder:validation:Enum=sentinel;redis
+	// The schema of redis service, "cluster" is only supported by external redis,
+	// and all the hosts are used as the seed nodes of redis cluster.
+	// +kubebuilder:validation:Enum=sentinel;redis;cluster
 	Schema string  `json:"schema,omitempty"`
 	Hosts  []Hosts `json:"hosts,omitempty"`
+	// The sentinel or standalone redis used by the harbor components which do not support redis cluster,
+	// i.e. all of them for now, the redis cluster is only checked by the operator. It is required by the cluster schema.
+	// +optional
+	Fallback *RedisFallback `json:"fallback,omitempty"`
+
+	// Metrics options of inCluster redis.
+	// +optional
+	Metrics *RedisMetrics `json:"metrics,omitempty"`
+}
+
+type RedisMetrics struct {
+	// Enable the redis exporter sidecar, a ServiceMonitor will be created if the CRD is installed.
+	Enabled bool `json:"enabled,omitempty"`
+	// The image of redis exporter, use the default image of redis operator if not set.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Interval at which metrics should be scraped, e.g. "30s".
+	// +optional
+	Interval string `json:"interval,omitempty"`
+}
+
+type RedisFallback struct {
+	// The schema of the fallback redis, sentinel or redis.
+	// +kubebuilder:validation:Enum=sentinel;redis
+	Schema string `json:"schema"`
+	// +kubebuilder:validation:MinItems=1
+	Hosts []Hosts `json:"hosts"`
+	// The secret must contains "password".
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// The master group name, it is required by the sentinel schema.
+	// +optional
+	GroupName string `json:"groupName,omitempty"`
 }
 
 type Hosts struct {
@@ -331,6 +669,13 @@ type Hosts struct {
 
 type Sentinel struct {
 	Replicas int `json:"replicas,omitempty"`
+	// If provided, use these requests and limit for cpu/memory resource allocation of sentinel.
+	// Fall back to the resources of redis server if not set.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Sentinel configuration directives, e.g. "down-after-milliseconds": "5000".
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
 }
 
 type RedisServer struct {
@@ -339,6 +684,13 @@ type RedisServer struct {
 	StorageClassName string                      `json:"storageClassName,omitempty"`
 	// the size of storage used in redis.
 	Storage string `json:"storage,omitempty"`
+	// Keep the persistent volume claims of redis after the redis is deleted.
+	// +optional
+	KeepAfterDeletion bool `json:"keepAfterDeletion,omitempty"`
+	// Redis configuration directives, e.g. "maxmemory": "1gb", "maxmemory-policy": "allkeys-lru",
+	// "appendonly": "yes" or "save": "900 1 300 10".
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
 }
 
 type ChartMuseum struct {
@@ -378,6 +730,168 @@ type HarborClusterStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	Conditions []HarborClusterCondition `json:"conditions,omitempty"`
+
+	// The last successful backup of each harbor database.
+	// +optional
+	DatabaseBackups []DatabaseBackupStatus `json:"databaseBackups,omitempty"`
+
+	// The progress of the last database restore.
+	// +optional
+	DatabaseRestore *DatabaseRestoreStatus `json:"databaseRestore,omitempty"`
+
+	// The progress of the database credential rotation of each harbor component.
+	// +optional
+	DatabaseCredentials []DatabaseCredentialStatus `json:"databaseCredentials,omitempty"`
+
+	// The harbor databases whose objects have been transferred from the legacy owners to the component users,
+	// the transfer runs once for each database.
+	// +optional
+	DatabaseOwnershipMigrated []string `json:"databaseOwnershipMigrated,omitempty"`
+
+	// The current properties of the buckets of inCluster storage.
+	// +optional
+	StorageBuckets []StorageBucketStatus `json:"storageBuckets,omitempty"`
+
+	// The progress of the last storage migration.
+	// +optional
+	StorageMigration *StorageMigrationStatus `json:"storageMigration,omitempty"`
+
+	// The usage and drive health of inCluster storage, it is collected periodically.
+	// +optional
+	StorageUsage *StorageUsageStatus `json:"storageUsage,omitempty"`
+
+	// The result of the last probe of external storage.
+	// +optional
+	StorageProbe *StorageProbeStatus `json:"storageProbe,omitempty"`
+}
+
+type StorageProbeStatus struct {
+	// The checksum of the storage spec probed, the storage is probed again once it is changed.
+	Checksum string `json:"checksum"`
+	// The failing capability of the last probe, it is empty if the probe passed.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// The last time the storage is probed.
+	LastProbeTime metav1.Time `json:"lastProbeTime"`
+}
+
+type StorageUsageStatus struct {
+	// The size and object count of each bucket.
+	// +optional
+	Buckets []StorageBucketUsage `json:"buckets,omitempty"`
+	// The bytes used on the drives of minIO, including the parity of erasure code.
+	UsedBytes int64 `json:"usedBytes"`
+	// The provisioned capacity of the volumes of minIO in bytes.
+	CapacityBytes int64 `json:"capacityBytes"`
+	// The percentage of UsedBytes in CapacityBytes.
+	UsedPercentage int `json:"usedPercentage"`
+	// The number of online drives.
+	OnlineDrives int `json:"onlineDrives"`
+	// The number of offline drives.
+	OfflineDrives int `json:"offlineDrives"`
+	// The last time the usage is collected.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
+}
+
+type StorageBucketUsage struct {
+	// The name of bucket.
+	Name string `json:"name"`
+	// The total size of objects in bytes.
+	Size int64 `json:"size"`
+	// The number of objects.
+	Objects int64 `json:"objects"`
+}
+
+type StorageMigrationStatus struct {
+	// The storage kind migrated from.
+	Source string `json:"source"`
+	// The storage kind migrated to.
+	Target string `json:"target"`
+	// The phase of migration, ReadOnly, Copying or Completed.
+	Phase string `json:"phase"`
+	// The directory being copied, registry or chartmuseum.
+	// +optional
+	Directory string `json:"directory,omitempty"`
+	// The key of the last object copied and verified in the directory, the copy resumes after it.
+	// +optional
+	Checkpoint string `json:"checkpoint,omitempty"`
+	// The number of objects copied.
+	// +optional
+	CopiedObjects int64 `json:"copiedObjects,omitempty"`
+	// The bytes of objects copied.
+	// +optional
+	CopiedBytes int64 `json:"copiedBytes,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+type StorageBucketStatus struct {
+	// The name of bucket.
+	Name string `json:"name"`
+	// The versioning of bucket, Enabled, Suspended or empty if it is never enabled.
+	// +optional
+	Versioning string `json:"versioning,omitempty"`
+	// The hard quota of bucket in bytes.
+	// +optional
+	Quota int64 `json:"quota,omitempty"`
+	// Whether object locking is enabled.
+	// +optional
+	ObjectLocking bool `json:"objectLocking,omitempty"`
+}
+
+type DatabaseCredentialStatus struct {
+	// The harbor component, core, clair, notaryServer or notarySigner.
+	Component string `json:"component"`
+	// The last handled value of annotation goharbor.io/database-credential-rotation,
+	// it is kept by scheduled rotations.
+	// +optional
+	RotationID string `json:"rotationID,omitempty"`
+	// The login role used by the component, it alternates between the database owner
+	// and its alternate role on each rotation. Empty means the database owner.
+	// +optional
+	User string `json:"user,omitempty"`
+	// The phase of the last rotation, Rolling, Completed or Failed.
+	// +optional
+	Phase string `json:"phase,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Time of the last rotation started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Time of the last successful rotation.
+	// +optional
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+}
+
+type DatabaseBackupStatus struct {
+	// The name of database.
+	Database string `json:"database"`
+	// Time of the last successful backup.
+	// +optional
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+	// Location of the last successful backup.
+	// +optional
+	Location string `json:"location,omitempty"`
+}
+
+type DatabaseRestoreStatus struct {
+	// The id of restore, "initial" for the restore on creation,
+	// otherwise the value of annotation goharbor.io/database-restore.
+	ID string `json:"id"`
+	// The phase of restore, Stopping, Restoring, Verifying, Completed or Failed.
+	Phase string `json:"phase"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
 }
 
 // HarborClusterConditionType is a valid value for HarborClusterConditionType.Type
@@ -395,6 +909,14 @@ const (
 	StorageReady HarborClusterConditionType = "StorageReady"
 	// ServiceReady means the Service of Harbor is ready.
 	ServiceReady HarborClusterConditionType = "ServiceReady"
+	// DatabaseBackupReady means the last backups of Database are successful.
+	DatabaseBackupReady HarborClusterConditionType = "DatabaseBackupReady"
+	// DatabaseRestored means the last restore of Database is completed.
+	DatabaseRestored HarborClusterConditionType = "DatabaseRestored"
+	// DatabaseCredentialRotated means the last credential rotations of Database are completed.
+	DatabaseCredentialRotated HarborClusterConditionType = "DatabaseCredentialRotated"
+	// StorageUsageWarning means the usage of inCluster storage is above the warning percentage, or drives are offline.
+	StorageUsageWarning HarborClusterConditionType = "StorageUsageWarning"
 )
 
 // HarborClusterCondition contains details for the current condition of this pod.
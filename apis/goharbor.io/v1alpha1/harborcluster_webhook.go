@@ -17,7 +17,11 @@ package v1alpha1
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -55,15 +59,110 @@ var _ webhook.Validator = &HarborCluster{}
 func (r *HarborCluster) ValidateCreate() error {
 	harborclusterlog.Info("validate create", "name", r.Name)
 
-	// TODO(user): fill in your validation logic upon object creation.
-	return nil
+	if err := r.ValidateDatabaseBackup(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateDatabaseRestore(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateDatabaseWal(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateDatabasePatroni(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateDatabaseCredentialRotation(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateDatabaseScale(nil); err != nil {
+		return err
+	}
+
+	if err := r.ValidateStorageBucketPolicy(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateStorageZones(nil); err != nil {
+		return err
+	}
+
+	if err := r.ValidateFileSystemStorage(nil); err != nil {
+		return err
+	}
+
+	if err := r.ValidateStorageCredentials(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateRedisScale(nil); err != nil {
+		return err
+	}
+
+	return r.ValidateRedisSchema()
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *HarborCluster) ValidateUpdate(old runtime.Object) error {
 	harborclusterlog.Info("validate update", "name", r.Name)
 
-	return r.ValidateComponentKind(old)
+	if err := r.ValidateComponentKind(old); err != nil {
+		return err
+	}
+
+	if err := r.ValidateDatabaseBackup(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateDatabaseRestore(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateDatabaseWal(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateDatabasePatroni(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateDatabaseCredentialRotation(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateDatabaseScale(old); err != nil {
+		return err
+	}
+
+	if err := r.ValidateStorageLayout(old); err != nil {
+		return err
+	}
+
+	if err := r.ValidateStorageBucketPolicy(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateStorageZones(old); err != nil {
+		return err
+	}
+
+	if err := r.ValidateFileSystemStorage(old); err != nil {
+		return err
+	}
+
+	if err := r.ValidateStorageCredentials(); err != nil {
+		return err
+	}
+
+	if err := r.ValidateRedisScale(old); err != nil {
+		return err
+	}
+
+	return r.ValidateRedisSchema()
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -77,10 +176,33 @@ func (r *HarborCluster) ValidateDelete() error {
 func (r *HarborCluster) ValidateComponentKind(old runtime.Object) error {
 	oldHarbor := old.(*HarborCluster)
 	if r.Spec.Redis.Kind != oldHarbor.Spec.Redis.Kind ||
-		r.Spec.Database.Kind != oldHarbor.Spec.Database.Kind ||
-		r.Spec.Storage.Kind != oldHarbor.Spec.Storage.Kind {
+		r.Spec.Database.Kind != oldHarbor.Spec.Database.Kind {
 		return errors.New("service kind switching is not supported")
 	}
+
+	if r.Spec.Storage.Kind != oldHarbor.Spec.Storage.Kind {
+		return r.ValidateStorageMigration(oldHarbor)
+	}
+	return nil
+}
+
+// MigratableStorageKinds are the storage kinds compatible with S3 API, the objects can be migrated between them.
+var MigratableStorageKinds = map[string]bool{
+	"inCluster": true,
+	"s3":        true,
+	"oss":       true,
+}
+
+// ValidateStorageMigration checks the storage kind can be switched, the objects are migrated by the operator.
+func (r *HarborCluster) ValidateStorageMigration(oldHarbor *HarborCluster) error {
+	if !MigratableStorageKinds[oldHarbor.Spec.Storage.Kind] || !MigratableStorageKinds[r.Spec.Storage.Kind] {
+		return fmt.Errorf("storage kind switching from %s to %s is not supported, only inCluster, s3 and oss can be migrated",
+			oldHarbor.Spec.Storage.Kind, r.Spec.Storage.Kind)
+	}
+
+	if migration := oldHarbor.Status.StorageMigration; migration != nil && migration.CompletionTime == nil {
+		return fmt.Errorf("storage migration from %s to %s is in progress", migration.Source, migration.Target)
+	}
 	return nil
 }
 
@@ -90,3 +212,383 @@ func (r *HarborCluster) ValidateCertificateIssuerRef() error {
 	}
 	return nil
 }
+
+const (
+	// MinRedisSentinelReplicas keeps the sentinel quorum (replicas/2+1) able to
+	// agree on a failover when one sentinel is lost.
+	MinRedisSentinelReplicas = 3
+	MinRedisServerReplicas   = 1
+)
+
+// ValidateRedisScale checks the replicas of inCluster redis server and sentinel, 0 replicas means the default replicas.
+// The minimum sentinel replicas applies on creation, the existing sentinels below it are kept
+// but they can not be scaled down any further on update.
+func (r *HarborCluster) ValidateRedisScale(old runtime.Object) error {
+	if r.Spec.Redis == nil || r.Spec.Redis.Kind != InClusterComponent || r.Spec.Redis.Spec == nil {
+		return nil
+	}
+
+	if server := r.Spec.Redis.Spec.Server; server != nil && server.Replicas != 0 && server.Replicas < MinRedisServerReplicas {
+		return fmt.Errorf("redis server replicas %d must not be less than %d, or 0 for the default", server.Replicas, MinRedisServerReplicas)
+	}
+
+	sentinel := r.Spec.Redis.Spec.Sentinel
+	if sentinel == nil || sentinel.Replicas == 0 || sentinel.Replicas >= MinRedisSentinelReplicas {
+		return nil
+	}
+
+	oldHarbor, ok := old.(*HarborCluster)
+	if !ok {
+		return fmt.Errorf("redis sentinel replicas %d must not be less than %d, or 0 for the default", sentinel.Replicas, MinRedisSentinelReplicas)
+	}
+
+	// the default sentinel replicas is the minimum
+	oldReplicas := MinRedisSentinelReplicas
+	if oldHarbor.Spec.Redis != nil && oldHarbor.Spec.Redis.Spec != nil && oldHarbor.Spec.Redis.Spec.Sentinel != nil &&
+		oldHarbor.Spec.Redis.Spec.Sentinel.Replicas != 0 {
+		oldReplicas = oldHarbor.Spec.Redis.Spec.Sentinel.Replicas
+	}
+	if sentinel.Replicas < oldReplicas {
+		return fmt.Errorf("redis sentinel can not be scaled down from %d to %d, which is less than %d", oldReplicas, sentinel.Replicas, MinRedisSentinelReplicas)
+	}
+	return nil
+}
+
+// RedisClusterSupportedComponents are the harbor components which are able to connect to redis cluster,
+// the others connect to the fallback redis. None of them is able to for now: harbor operator renders
+// the redis url of registry and chartmuseum as a single address and a db, so that the seed list is not understood.
+var RedisClusterSupportedComponents = map[string]bool{
+	"core":        false,
+	"jobservice":  false,
+	"registry":    false,
+	"chartmuseum": false,
+	"clair":       false,
+}
+
+// ValidateRedisSchema checks the redis cluster schema is used by external redis, with a fallback redis
+// for the harbor components which do not support redis cluster.
+func (r *HarborCluster) ValidateRedisSchema() error {
+	if r.Spec.Redis == nil || r.Spec.Redis.Spec == nil || r.Spec.Redis.Spec.Schema != RedisClusterSchema {
+		return nil
+	}
+
+	if r.Spec.Redis.Kind != ExternalComponent {
+		return errors.New("redis cluster schema is only supported by external redis")
+	}
+	if len(r.Spec.Redis.Spec.Hosts) < 1 {
+		return errors.New("redis cluster schema requires at least one seed host")
+	}
+
+	fallback := r.Spec.Redis.Spec.Fallback
+	if fallback == nil {
+		return errors.New("redis cluster schema requires a fallback redis for the harbor components")
+	}
+	switch fallback.Schema {
+	case RedisSentinelSchema:
+		if len(fallback.Hosts) < 1 || fallback.GroupName == "" {
+			return errors.New("sentinel fallback redis requires hosts and groupName")
+		}
+	case RedisServerSchema:
+		if len(fallback.Hosts) != 1 {
+			return errors.New("standalone fallback redis requires exactly one host")
+		}
+	default:
+		return fmt.Errorf("fallback redis schema %q is invalid, it must be sentinel or redis", fallback.Schema)
+	}
+	return nil
+}
+
+// ValidateDatabaseBackup checks the backup target of harbor databases.
+func (r *HarborCluster) ValidateDatabaseBackup() error {
+	if r.Spec.Database == nil || r.Spec.Database.Spec == nil || r.Spec.Database.Spec.Backup == nil {
+		return nil
+	}
+
+	target := r.Spec.Database.Spec.Backup.Target
+	switch target.Kind {
+	case "pvc":
+		if target.ClaimName == "" {
+			return errors.New("database backup target claimName is required by pvc kind")
+		}
+	case "storage":
+		if r.Spec.Storage == nil || (r.Spec.Storage.Kind != InClusterComponent && r.Spec.Storage.Kind != "s3") {
+			return errors.New("database backup to storage requires inCluster or s3 storage")
+		}
+		if r.Spec.Storage.Kind == "s3" && r.Spec.Storage.S3 != nil && r.Spec.Storage.S3.RoleArn != "" {
+			return errors.New("database backup to storage requires the static keys of s3 storage")
+		}
+	}
+	return nil
+}
+
+// ValidateDatabaseRestore checks the backups to restore are the locations of harbor database backups.
+func (r *HarborCluster) ValidateDatabaseRestore() error {
+	if r.Spec.Database == nil || r.Spec.Database.Spec == nil || r.Spec.Database.Spec.Restore == nil {
+		return nil
+	}
+
+	for database, location := range r.Spec.Database.Spec.Restore.Backups {
+		switch database {
+		case "core", "clair", "notaryserver", "notarysigner":
+		default:
+			return fmt.Errorf("database %s is not a harbor database", database)
+		}
+		if !strings.HasPrefix(location, "s3://") && !strings.HasPrefix(location, "pvc://") {
+			return fmt.Errorf("backup location %s of database %s must start with s3:// or pvc://", location, database)
+		}
+	}
+	return nil
+}
+
+// ValidateDatabaseWal checks WAL archiving and clone are used by inCluster database with S3 compatible storage.
+func (r *HarborCluster) ValidateDatabaseWal() error {
+	if r.Spec.Database == nil || r.Spec.Database.Spec == nil {
+		return nil
+	}
+
+	spec := r.Spec.Database.Spec
+	if (spec.WalArchive == nil || !spec.WalArchive.Enabled) && spec.Clone == nil {
+		return nil
+	}
+
+	if r.Spec.Database.Kind != InClusterComponent {
+		return errors.New("database WAL archiving and clone are only supported by inCluster database")
+	}
+	if r.Spec.Storage == nil || (r.Spec.Storage.Kind != InClusterComponent && r.Spec.Storage.Kind != "s3") {
+		return errors.New("database WAL archiving and clone require inCluster or s3 storage")
+	}
+	if r.Spec.Storage.Kind == "s3" && r.Spec.Storage.S3 != nil && r.Spec.Storage.S3.RoleArn != "" {
+		return errors.New("database WAL archiving and clone require the static keys of s3 storage")
+	}
+	return nil
+}
+
+// ValidateDatabasePatroni checks the leader lock of Patroni does not expire before the retries,
+// the defaults of Patroni are used for the settings not set.
+func (r *HarborCluster) ValidateDatabasePatroni() error {
+	if r.Spec.Database == nil || r.Spec.Database.Spec == nil || r.Spec.Database.Spec.Patroni == nil {
+		return nil
+	}
+
+	patroni := r.Spec.Database.Spec.Patroni
+	ttl, loopWait, retryTimeout := uint32(30), uint32(10), uint32(10)
+	if patroni.TTL > 0 {
+		ttl = patroni.TTL
+	}
+	if patroni.LoopWait > 0 {
+		loopWait = patroni.LoopWait
+	}
+	if patroni.RetryTimeout > 0 {
+		retryTimeout = patroni.RetryTimeout
+	}
+
+	if loopWait+2*retryTimeout > ttl {
+		return fmt.Errorf("patroni ttl %d must not be less than loopWait + 2 * retryTimeout (%d)", ttl, loopWait+2*retryTimeout)
+	}
+	return nil
+}
+
+// ValidateDatabaseCredentialRotation checks the interval and components of database credential rotation.
+func (r *HarborCluster) ValidateDatabaseCredentialRotation() error {
+	if r.Spec.Database != nil && r.Spec.Database.Spec != nil && r.Spec.Database.Spec.CredentialRotation != nil {
+		if r.Spec.Database.Spec.CredentialRotation.Interval.Duration <= 0 {
+			return errors.New("database credential rotation interval must be positive")
+		}
+	}
+
+	components := r.Annotations[DatabaseCredentialRotationComponentsAnnotation]
+	if components == "" {
+		return nil
+	}
+	for _, component := range strings.Split(components, ",") {
+		switch strings.TrimSpace(component) {
+		case "core", "clair", "notaryServer", "notarySigner":
+		default:
+			return fmt.Errorf("unknown component %q in annotation %s", component, DatabaseCredentialRotationComponentsAnnotation)
+		}
+	}
+	return nil
+}
+
+// MinDatabaseReplicas is the minimum number of instances of inCluster database, 0 replicas means the default instances.
+const MinDatabaseReplicas = 1
+
+// ValidateDatabaseScale checks the instances of inCluster database, and rejects shrinking the volume on update.
+func (r *HarborCluster) ValidateDatabaseScale(old runtime.Object) error {
+	if r.Spec.Database == nil || r.Spec.Database.Kind != InClusterComponent || r.Spec.Database.Spec == nil {
+		return nil
+	}
+
+	if replicas := r.Spec.Database.Spec.Replicas; replicas != 0 && replicas < MinDatabaseReplicas {
+		return fmt.Errorf("database replicas %d must not be less than %d, or 0 for the default", replicas, MinDatabaseReplicas)
+	}
+
+	oldHarbor, ok := old.(*HarborCluster)
+	if !ok || oldHarbor.Spec.Database == nil || oldHarbor.Spec.Database.Spec == nil ||
+		oldHarbor.Spec.Database.Spec.Storage == "" || r.Spec.Database.Spec.Storage == "" {
+		return nil
+	}
+
+	size, err := resource.ParseQuantity(r.Spec.Database.Spec.Storage)
+	if err != nil {
+		return fmt.Errorf("invalid database storage %q: %v", r.Spec.Database.Spec.Storage, err)
+	}
+	oldSize, err := resource.ParseQuantity(oldHarbor.Spec.Database.Spec.Storage)
+	if err != nil {
+		return nil
+	}
+	if size.Cmp(oldSize) < 0 {
+		return fmt.Errorf("database storage can not be shrunk from %s to %s", oldHarbor.Spec.Database.Spec.Storage, r.Spec.Database.Spec.Storage)
+	}
+	return nil
+}
+
+// ValidateStorageLayout rejects changing the buckets and root directory of inCluster storage,
+// the objects stored before would not be found by harbor.
+func (r *HarborCluster) ValidateStorageLayout(old runtime.Object) error {
+	oldHarbor, ok := old.(*HarborCluster)
+	if !ok || r.Spec.Storage == nil || oldHarbor.Spec.Storage == nil ||
+		r.Spec.Storage.Kind != InClusterComponent || oldHarbor.Spec.Storage.Kind != InClusterComponent {
+		return nil
+	}
+
+	var spec, oldSpec MinIOSpec
+	if r.Spec.Storage.InCluster != nil && r.Spec.Storage.InCluster.Spec != nil {
+		spec = *r.Spec.Storage.InCluster.Spec
+	}
+	if oldHarbor.Spec.Storage.InCluster != nil && oldHarbor.Spec.Storage.InCluster.Spec != nil {
+		oldSpec = *oldHarbor.Spec.Storage.InCluster.Spec
+	}
+
+	if spec.Bucket != oldSpec.Bucket || spec.ChartMuseumBucket != oldSpec.ChartMuseumBucket ||
+		strings.Trim(spec.RootDirectory, "/") != strings.Trim(oldSpec.RootDirectory, "/") {
+		return errors.New("buckets and root directory of inCluster storage can not be changed")
+	}
+
+	locking := spec.BucketPolicy != nil && spec.BucketPolicy.ObjectLocking
+	oldLocking := oldSpec.BucketPolicy != nil && oldSpec.BucketPolicy.ObjectLocking
+	if locking != oldLocking {
+		return errors.New("object locking of inCluster storage can only be set on creation")
+	}
+
+	if spec.EnableTLS != oldSpec.EnableTLS {
+		return errors.New("TLS of inCluster storage can only be set on creation")
+	}
+	return nil
+}
+
+// ValidateStorageBucketPolicy checks object locking of inCluster storage is enabled with versioning.
+func (r *HarborCluster) ValidateStorageBucketPolicy() error {
+	if r.Spec.Storage == nil || r.Spec.Storage.InCluster == nil || r.Spec.Storage.InCluster.Spec == nil {
+		return nil
+	}
+
+	policy := r.Spec.Storage.InCluster.Spec.BucketPolicy
+	if policy != nil && policy.ObjectLocking && !policy.Versioning {
+		return errors.New("object locking of inCluster storage requires versioning")
+	}
+	return nil
+}
+
+// ValidateStorageZones checks the zones of inCluster storage, the capacity is expanded by appending zones,
+// since minIO can not resize the erasure sets of the existing zones.
+func (r *HarborCluster) ValidateStorageZones(old runtime.Object) error {
+	if r.Spec.Storage == nil || r.Spec.Storage.Kind != InClusterComponent ||
+		r.Spec.Storage.InCluster == nil || r.Spec.Storage.InCluster.Spec == nil {
+		return nil
+	}
+
+	spec := r.Spec.Storage.InCluster.Spec
+	// "zone-harbor" is the name of the first zone
+	names := map[string]bool{"zone-harbor": true}
+	for _, zone := range spec.Zones {
+		if names[zone.Name] {
+			return fmt.Errorf("duplicated zone %s of inCluster storage", zone.Name)
+		}
+		names[zone.Name] = true
+
+		if zone.Servers*zone.VolumesPerServer < 4 {
+			return fmt.Errorf("zone %s of inCluster storage requires at least 4 volumes in total", zone.Name)
+		}
+	}
+
+	oldHarbor, ok := old.(*HarborCluster)
+	if !ok || oldHarbor.Spec.Storage == nil || oldHarbor.Spec.Storage.Kind != InClusterComponent ||
+		oldHarbor.Spec.Storage.InCluster == nil || oldHarbor.Spec.Storage.InCluster.Spec == nil {
+		return nil
+	}
+
+	oldSpec := oldHarbor.Spec.Storage.InCluster.Spec
+	if spec.Replicas != oldSpec.Replicas || spec.VolumesPerServer != oldSpec.VolumesPerServer {
+		return errors.New("replicas and volumesPerServer of inCluster storage can not be changed, append a zone to expand the capacity")
+	}
+
+	if len(spec.Zones) < len(oldSpec.Zones) {
+		return errors.New("zones of inCluster storage can not be removed")
+	}
+	for i, zone := range oldSpec.Zones {
+		if spec.Zones[i].Name != zone.Name || spec.Zones[i].Servers != zone.Servers ||
+			spec.Zones[i].VolumesPerServer != zone.VolumesPerServer {
+			return fmt.Errorf("zone %s of inCluster storage can not be modified", zone.Name)
+		}
+	}
+	return nil
+}
+
+// ValidateFileSystemStorage checks the claim of filesystem storage, it can only be expanded on update.
+func (r *HarborCluster) ValidateFileSystemStorage(old runtime.Object) error {
+	if r.Spec.Storage == nil || r.Spec.Storage.Kind != "filesystem" {
+		return nil
+	}
+
+	fs := r.Spec.Storage.FileSystem
+	if fs == nil {
+		return errors.New("filesystem options are required by filesystem storage")
+	}
+	if fs.AccessMode == corev1.ReadWriteOnce && r.Spec.Replicas > 1 {
+		return fmt.Errorf("%s filesystem storage requires a single replica, but replicas is %d", fs.AccessMode, r.Spec.Replicas)
+	}
+
+	oldHarbor, ok := old.(*HarborCluster)
+	if !ok || oldHarbor.Spec.Storage == nil || oldHarbor.Spec.Storage.Kind != "filesystem" ||
+		oldHarbor.Spec.Storage.FileSystem == nil {
+		return nil
+	}
+
+	oldFs := oldHarbor.Spec.Storage.FileSystem
+	if fs.Size.Cmp(oldFs.Size) < 0 {
+		return fmt.Errorf("filesystem storage can not be shrunk from %s to %s", oldFs.Size.String(), fs.Size.String())
+	}
+	if fs.StorageClassName != oldFs.StorageClassName || fs.AccessMode != oldFs.AccessMode {
+		return errors.New("storage class and access mode of filesystem storage can not be changed")
+	}
+	return nil
+}
+
+// ValidateStorageCredentials checks the external storage is accessed either by static keys or by workload identity.
+func (r *HarborCluster) ValidateStorageCredentials() error {
+	if r.Spec.Storage == nil {
+		return nil
+	}
+
+	switch r.Spec.Storage.Kind {
+	case "s3":
+		s3 := r.Spec.Storage.S3
+		if s3 == nil {
+			return nil
+		}
+		hasKeys := s3.AccessKey != "" || s3.SecretKey != ""
+		if s3.RoleArn != "" && hasKeys {
+			return errors.New("s3 storage can not use both the static keys and rolearn")
+		}
+		if s3.RoleArn == "" && (s3.AccessKey == "" || s3.SecretKey == "") {
+			return errors.New("s3 storage requires accesskey and secretkey, or rolearn with IRSA")
+		}
+	case "gcs":
+		gcs := r.Spec.Storage.Gcs
+		if gcs != nil && gcs.ServiceAccount != "" && gcs.EncodedKey != "" {
+			return errors.New("gcs storage can not use both encodedkey and serviceaccount")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,490 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestValidateRedisSchema(t *testing.T) {
+	seeds := []Hosts{{Host: "redis-0", Port: "6379"}, {Host: "redis-1", Port: "6379"}}
+
+	cases := []struct {
+		name    string
+		redis   *Redis
+		wantErr bool
+	}{
+		{
+			name:  "redis is not set",
+			redis: nil,
+		},
+		{
+			name:  "sentinel schema is not validated",
+			redis: &Redis{Kind: InClusterComponent, Spec: &RedisSpec{Schema: RedisSentinelSchema}},
+		},
+		{
+			name:    "cluster schema of inCluster redis",
+			redis:   &Redis{Kind: InClusterComponent, Spec: &RedisSpec{Schema: RedisClusterSchema, Hosts: seeds}},
+			wantErr: true,
+		},
+		{
+			name:    "cluster schema without seed hosts",
+			redis:   &Redis{Kind: ExternalComponent, Spec: &RedisSpec{Schema: RedisClusterSchema}},
+			wantErr: true,
+		},
+		{
+			name:    "cluster schema without fallback",
+			redis:   &Redis{Kind: ExternalComponent, Spec: &RedisSpec{Schema: RedisClusterSchema, Hosts: seeds}},
+			wantErr: true,
+		},
+		{
+			name: "sentinel fallback without group name",
+			redis: &Redis{Kind: ExternalComponent, Spec: &RedisSpec{
+				Schema:   RedisClusterSchema,
+				Hosts:    seeds,
+				Fallback: &RedisFallback{Schema: RedisSentinelSchema, Hosts: seeds},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "sentinel fallback",
+			redis: &Redis{Kind: ExternalComponent, Spec: &RedisSpec{
+				Schema:   RedisClusterSchema,
+				Hosts:    seeds,
+				Fallback: &RedisFallback{Schema: RedisSentinelSchema, Hosts: seeds, GroupName: "mymaster"},
+			}},
+		},
+		{
+			name: "standalone fallback with several hosts",
+			redis: &Redis{Kind: ExternalComponent, Spec: &RedisSpec{
+				Schema:   RedisClusterSchema,
+				Hosts:    seeds,
+				Fallback: &RedisFallback{Schema: RedisServerSchema, Hosts: seeds},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "standalone fallback",
+			redis: &Redis{Kind: ExternalComponent, Spec: &RedisSpec{
+				Schema:   RedisClusterSchema,
+				Hosts:    seeds,
+				Fallback: &RedisFallback{Schema: RedisServerSchema, Hosts: seeds[:1]},
+			}},
+		},
+		{
+			name: "cluster fallback",
+			redis: &Redis{Kind: ExternalComponent, Spec: &RedisSpec{
+				Schema:   RedisClusterSchema,
+				Hosts:    seeds,
+				Fallback: &RedisFallback{Schema: RedisClusterSchema, Hosts: seeds},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := &HarborCluster{Spec: HarborClusterSpec{Redis: c.redis}}
+			if err := r.ValidateRedisSchema(); (err != nil) != c.wantErr {
+				t.Errorf("ValidateRedisSchema() error = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRedisScale(t *testing.T) {
+	sentinels := func(replicas int) *HarborCluster {
+		return &HarborCluster{Spec: HarborClusterSpec{Redis: &Redis{
+			Kind: InClusterComponent,
+			Spec: &RedisSpec{Sentinel: &Sentinel{Replicas: replicas}},
+		}}}
+	}
+
+	cases := []struct {
+		name    string
+		new     *HarborCluster
+		old     *HarborCluster
+		wantErr bool
+	}{
+		{
+			name: "external redis is not validated",
+			new: &HarborCluster{Spec: HarborClusterSpec{Redis: &Redis{
+				Kind: ExternalComponent,
+				Spec: &RedisSpec{Sentinel: &Sentinel{Replicas: 1}},
+			}}},
+		},
+		{
+			name: "default replicas",
+			new: &HarborCluster{Spec: HarborClusterSpec{Redis: &Redis{
+				Kind: InClusterComponent,
+				Spec: &RedisSpec{Server: &RedisServer{}, Sentinel: &Sentinel{}},
+			}}},
+		},
+		{
+			name: "negative server replicas",
+			new: &HarborCluster{Spec: HarborClusterSpec{Redis: &Redis{
+				Kind: InClusterComponent,
+				Spec: &RedisSpec{Server: &RedisServer{Replicas: -1}},
+			}}},
+			wantErr: true,
+		},
+		{
+			name:    "create sentinels below the quorum",
+			new:     sentinels(MinRedisSentinelReplicas - 1),
+			wantErr: true,
+		},
+		{
+			name: "create the minimum sentinels",
+			new:  sentinels(MinRedisSentinelReplicas),
+		},
+		{
+			name: "keep the existing sentinels below the quorum",
+			new:  sentinels(1),
+			old:  sentinels(1),
+		},
+		{
+			name: "scale up the existing sentinels below the quorum",
+			new:  sentinels(2),
+			old:  sentinels(1),
+		},
+		{
+			name:    "scale down the sentinels below the quorum",
+			new:     sentinels(1),
+			old:     sentinels(2),
+			wantErr: true,
+		},
+		{
+			name:    "scale down the default sentinels below the quorum",
+			new:     sentinels(2),
+			old:     sentinels(0),
+			wantErr: true,
+		},
+		{
+			name: "scale down the sentinels to the quorum",
+			new:  sentinels(MinRedisSentinelReplicas),
+			old:  sentinels(5),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var old runtime.Object
+			if c.old != nil {
+				old = c.old
+			}
+			if err := c.new.ValidateRedisScale(old); (err != nil) != c.wantErr {
+				t.Errorf("ValidateRedisScale() error = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDatabaseScale(t *testing.T) {
+	database := func(replicas int, storage string) *HarborCluster {
+		return &HarborCluster{Spec: HarborClusterSpec{Database: &Database{
+			Kind: InClusterComponent,
+			Spec: &PostgresSQL{Replicas: replicas, Storage: storage},
+		}}}
+	}
+
+	cases := []struct {
+		name    string
+		new     *HarborCluster
+		old     *HarborCluster
+		wantErr bool
+	}{
+		{
+			name: "external database is not validated",
+			new: &HarborCluster{Spec: HarborClusterSpec{Database: &Database{
+				Kind: ExternalComponent,
+				Spec: &PostgresSQL{Replicas: -1},
+			}}},
+		},
+		{
+			name: "default replicas",
+			new:  database(0, "1Gi"),
+		},
+		{
+			name:    "negative replicas",
+			new:     database(-1, "1Gi"),
+			wantErr: true,
+		},
+		{
+			name: "expand the volume",
+			new:  database(2, "2Gi"),
+			old:  database(2, "1Gi"),
+		},
+		{
+			name: "same volume in another unit",
+			new:  database(2, "1024Mi"),
+			old:  database(2, "1Gi"),
+		},
+		{
+			name:    "shrink the volume",
+			new:     database(2, "500Mi"),
+			old:     database(2, "1Gi"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid volume size",
+			new:     database(2, "large"),
+			old:     database(2, "1Gi"),
+			wantErr: true,
+		},
+		{
+			name: "storage was not set",
+			new:  database(2, "1Gi"),
+			old:  database(2, ""),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var old runtime.Object
+			if c.old != nil {
+				old = c.old
+			}
+			if err := c.new.ValidateDatabaseScale(old); (err != nil) != c.wantErr {
+				t.Errorf("ValidateDatabaseScale() error = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStorageZones(t *testing.T) {
+	storage := func(replicas int32, zones ...MinIOZone) *HarborCluster {
+		return &HarborCluster{Spec: HarborClusterSpec{Storage: &Storage{
+			Kind: InClusterComponent,
+			InCluster: &InCluster{Spec: &MinIOSpec{
+				Replicas:         replicas,
+				VolumesPerServer: 1,
+				Zones:            zones,
+			}},
+		}}}
+	}
+	zone1 := MinIOZone{Name: "zone-1", Servers: 4, VolumesPerServer: 1}
+	zone2 := MinIOZone{Name: "zone-2", Servers: 2, VolumesPerServer: 2}
+
+	cases := []struct {
+		name    string
+		new     *HarborCluster
+		old     *HarborCluster
+		wantErr bool
+	}{
+		{
+			name: "external storage is not validated",
+			new:  &HarborCluster{Spec: HarborClusterSpec{Storage: &Storage{Kind: "s3"}}},
+		},
+		{
+			name: "create with zones",
+			new:  storage(4, zone1, zone2),
+		},
+		{
+			name:    "duplicated zones",
+			new:     storage(4, zone1, zone1),
+			wantErr: true,
+		},
+		{
+			name:    "zone named as the first zone",
+			new:     storage(4, MinIOZone{Name: "zone-harbor", Servers: 4, VolumesPerServer: 1}),
+			wantErr: true,
+		},
+		{
+			name:    "zone with less than 4 volumes",
+			new:     storage(4, MinIOZone{Name: "zone-1", Servers: 3, VolumesPerServer: 1}),
+			wantErr: true,
+		},
+		{
+			name: "append a zone",
+			new:  storage(4, zone1, zone2),
+			old:  storage(4, zone1),
+		},
+		{
+			name:    "change the replicas of the first zone",
+			new:     storage(8, zone1),
+			old:     storage(4, zone1),
+			wantErr: true,
+		},
+		{
+			name:    "remove a zone",
+			new:     storage(4, zone1),
+			old:     storage(4, zone1, zone2),
+			wantErr: true,
+		},
+		{
+			name:    "modify a zone",
+			new:     storage(4, MinIOZone{Name: "zone-1", Servers: 8, VolumesPerServer: 1}),
+			old:     storage(4, zone1),
+			wantErr: true,
+		},
+		{
+			name:    "reorder the zones",
+			new:     storage(4, zone2, zone1),
+			old:     storage(4, zone1, zone2),
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var old runtime.Object
+			if c.old != nil {
+				old = c.old
+			}
+			if err := c.new.ValidateStorageZones(old); (err != nil) != c.wantErr {
+				t.Errorf("ValidateStorageZones() error = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFileSystemStorage(t *testing.T) {
+	fileSystem := func(replicas int, size string, mode corev1.PersistentVolumeAccessMode) *HarborCluster {
+		return &HarborCluster{Spec: HarborClusterSpec{
+			Replicas: replicas,
+			Storage: &Storage{
+				Kind:       "filesystem",
+				FileSystem: &FileSystem{Size: resource.MustParse(size), AccessMode: mode},
+			},
+		}}
+	}
+
+	cases := []struct {
+		name    string
+		new     *HarborCluster
+		old     *HarborCluster
+		wantErr bool
+	}{
+		{
+			name: "object storage is not validated",
+			new:  &HarborCluster{Spec: HarborClusterSpec{Storage: &Storage{Kind: "s3"}}},
+		},
+		{
+			name:    "filesystem options are missing",
+			new:     &HarborCluster{Spec: HarborClusterSpec{Storage: &Storage{Kind: "filesystem"}}},
+			wantErr: true,
+		},
+		{
+			name: "ReadWriteMany with replicas",
+			new:  fileSystem(2, "10Gi", corev1.ReadWriteMany),
+		},
+		{
+			name:    "ReadWriteOnce with replicas",
+			new:     fileSystem(2, "10Gi", corev1.ReadWriteOnce),
+			wantErr: true,
+		},
+		{
+			name: "ReadWriteOnce with a single replica",
+			new:  fileSystem(1, "10Gi", corev1.ReadWriteOnce),
+		},
+		{
+			name: "expand the claim",
+			new:  fileSystem(1, "20Gi", ""),
+			old:  fileSystem(1, "10Gi", ""),
+		},
+		{
+			name:    "shrink the claim",
+			new:     fileSystem(1, "5Gi", ""),
+			old:     fileSystem(1, "10Gi", ""),
+			wantErr: true,
+		},
+		{
+			name:    "change the access mode",
+			new:     fileSystem(1, "10Gi", corev1.ReadWriteOnce),
+			old:     fileSystem(1, "10Gi", corev1.ReadWriteMany),
+			wantErr: true,
+		},
+		{
+			name: "switch from object storage",
+			new:  fileSystem(1, "10Gi", ""),
+			old:  &HarborCluster{Spec: HarborClusterSpec{Storage: &Storage{Kind: "s3"}}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var old runtime.Object
+			if c.old != nil {
+				old = c.old
+			}
+			if err := c.new.ValidateFileSystemStorage(old); (err != nil) != c.wantErr {
+				t.Errorf("ValidateFileSystemStorage() error = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStorageCredentials(t *testing.T) {
+	cases := []struct {
+		name    string
+		storage *Storage
+		wantErr bool
+	}{
+		{
+			name:    "s3 with static keys",
+			storage: &Storage{Kind: "s3", S3: &S3{AccessKey: "access", SecretKey: "secret"}},
+		},
+		{
+			name:    "s3 with rolearn",
+			storage: &Storage{Kind: "s3", S3: &S3{RoleArn: "arn:aws:iam::123456789012:role/harbor"}},
+		},
+		{
+			name:    "s3 with both static keys and rolearn",
+			storage: &Storage{Kind: "s3", S3: &S3{AccessKey: "access", SecretKey: "secret", RoleArn: "arn:aws:iam::123456789012:role/harbor"}},
+			wantErr: true,
+		},
+		{
+			name:    "s3 with access key only",
+			storage: &Storage{Kind: "s3", S3: &S3{AccessKey: "access"}},
+			wantErr: true,
+		},
+		{
+			name:    "s3 without credentials",
+			storage: &Storage{Kind: "s3", S3: &S3{}},
+			wantErr: true,
+		},
+		{
+			name:    "gcs with encoded key",
+			storage: &Storage{Kind: "gcs", Gcs: &Gcs{EncodedKey: "a2V5"}},
+		},
+		{
+			name:    "gcs with service account",
+			storage: &Storage{Kind: "gcs", Gcs: &Gcs{ServiceAccount: "harbor@project.iam.gserviceaccount.com"}},
+		},
+		{
+			name:    "gcs with both encoded key and service account",
+			storage: &Storage{Kind: "gcs", Gcs: &Gcs{EncodedKey: "a2V5", ServiceAccount: "harbor@project.iam.gserviceaccount.com"}},
+			wantErr: true,
+		},
+		{
+			name:    "inCluster storage is not validated",
+			storage: &Storage{Kind: InClusterComponent},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := &HarborCluster{Spec: HarborClusterSpec{Storage: c.storage}}
+			if err := r.ValidateStorageCredentials(); (err != nil) != c.wantErr {
+				t.Errorf("ValidateStorageCredentials() error = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
@@ -20,6 +20,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -93,6 +95,232 @@ func (in *Database) DeepCopy() *Database {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackup) DeepCopyInto(out *DatabaseBackup) {
+	*out = *in
+	out.Target = in.Target
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackup.
+func (in *DatabaseBackup) DeepCopy() *DatabaseBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupStatus) DeepCopyInto(out *DatabaseBackupStatus) {
+	*out = *in
+	if in.LastSuccessfulTime != nil {
+		in, out := &in.LastSuccessfulTime, &out.LastSuccessfulTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupStatus.
+func (in *DatabaseBackupStatus) DeepCopy() *DatabaseBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseBackupTarget) DeepCopyInto(out *DatabaseBackupTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseBackupTarget.
+func (in *DatabaseBackupTarget) DeepCopy() *DatabaseBackupTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseBackupTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseClone) DeepCopyInto(out *DatabaseClone) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseClone.
+func (in *DatabaseClone) DeepCopy() *DatabaseClone {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseClone)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseConnectionPooler) DeepCopyInto(out *DatabaseConnectionPooler) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxDBConnections != nil {
+		in, out := &in.MaxDBConnections, &out.MaxDBConnections
+		*out = new(int32)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseConnectionPooler.
+func (in *DatabaseConnectionPooler) DeepCopy() *DatabaseConnectionPooler {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseConnectionPooler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseCredentialRotation) DeepCopyInto(out *DatabaseCredentialRotation) {
+	*out = *in
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseCredentialRotation.
+func (in *DatabaseCredentialRotation) DeepCopy() *DatabaseCredentialRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseCredentialRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseCredentialStatus) DeepCopyInto(out *DatabaseCredentialStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseCredentialStatus.
+func (in *DatabaseCredentialStatus) DeepCopy() *DatabaseCredentialStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseCredentialStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabasePatroni) DeepCopyInto(out *DatabasePatroni) {
+	*out = *in
+	if in.MaximumLagOnFailover != nil {
+		in, out := &in.MaximumLagOnFailover, &out.MaximumLagOnFailover
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabasePatroni.
+func (in *DatabasePatroni) DeepCopy() *DatabasePatroni {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabasePatroni)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestore) DeepCopyInto(out *DatabaseRestore) {
+	*out = *in
+	if in.Backups != nil {
+		in, out := &in.Backups, &out.Backups
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestore.
+func (in *DatabaseRestore) DeepCopy() *DatabaseRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseRestoreStatus) DeepCopyInto(out *DatabaseRestoreStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseRestoreStatus.
+func (in *DatabaseRestoreStatus) DeepCopy() *DatabaseRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseWalArchive) DeepCopyInto(out *DatabaseWalArchive) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseWalArchive.
+func (in *DatabaseWalArchive) DeepCopy() *DatabaseWalArchive {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseWalArchive)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FileSystem) DeepCopyInto(out *FileSystem) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileSystem.
+func (in *FileSystem) DeepCopy() *FileSystem {
+	if in == nil {
+		return nil
+	}
+	out := new(FileSystem)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Gcs) DeepCopyInto(out *Gcs) {
 	*out = *in
@@ -259,6 +487,50 @@ func (in *HarborClusterStatus) DeepCopyInto(out *HarborClusterStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DatabaseBackups != nil {
+		in, out := &in.DatabaseBackups, &out.DatabaseBackups
+		*out = make([]DatabaseBackupStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DatabaseRestore != nil {
+		in, out := &in.DatabaseRestore, &out.DatabaseRestore
+		*out = new(DatabaseRestoreStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DatabaseCredentials != nil {
+		in, out := &in.DatabaseCredentials, &out.DatabaseCredentials
+		*out = make([]DatabaseCredentialStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DatabaseOwnershipMigrated != nil {
+		in, out := &in.DatabaseOwnershipMigrated, &out.DatabaseOwnershipMigrated
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StorageBuckets != nil {
+		in, out := &in.StorageBuckets, &out.StorageBuckets
+		*out = make([]StorageBucketStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.StorageMigration != nil {
+		in, out := &in.StorageMigration, &out.StorageMigration
+		*out = new(StorageMigrationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StorageUsage != nil {
+		in, out := &in.StorageUsage, &out.StorageUsage
+		*out = new(StorageUsageStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StorageProbe != nil {
+		in, out := &in.StorageProbe, &out.StorageProbe
+		*out = new(StorageProbeStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HarborClusterStatus.
@@ -336,11 +608,43 @@ func (in *JobService) DeepCopy() *JobService {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MinIOBucketPolicy) DeepCopyInto(out *MinIOBucketPolicy) {
+	*out = *in
+	if in.Quota != nil {
+		in, out := &in.Quota, &out.Quota
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MinIOBucketPolicy.
+func (in *MinIOBucketPolicy) DeepCopy() *MinIOBucketPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(MinIOBucketPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MinIOSpec) DeepCopyInto(out *MinIOSpec) {
 	*out = *in
 	in.VolumeClaimTemplate.DeepCopyInto(&out.VolumeClaimTemplate)
 	in.Resources.DeepCopyInto(&out.Resources)
+	if in.BucketPolicy != nil {
+		in, out := &in.BucketPolicy, &out.BucketPolicy
+		*out = new(MinIOBucketPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make([]MinIOZone, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MinIOSpec.
@@ -353,6 +657,31 @@ func (in *MinIOSpec) DeepCopy() *MinIOSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MinIOZone) DeepCopyInto(out *MinIOZone) {
+	*out = *in
+	if in.VolumeClaimTemplate != nil {
+		in, out := &in.VolumeClaimTemplate, &out.VolumeClaimTemplate
+		*out = new(v1.PersistentVolumeClaim)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MinIOZone.
+func (in *MinIOZone) DeepCopy() *MinIOZone {
+	if in == nil {
+		return nil
+	}
+	out := new(MinIOZone)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Notary) DeepCopyInto(out *Notary) {
 	*out = *in
@@ -387,6 +716,53 @@ func (in *Oss) DeepCopy() *Oss {
 func (in *PostgresSQL) DeepCopyInto(out *PostgresSQL) {
 	*out = *in
 	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(DatabaseBackup)
+		**out = **in
+	}
+	if in.Restore != nil {
+		in, out := &in.Restore, &out.Restore
+		*out = new(DatabaseRestore)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WalArchive != nil {
+		in, out := &in.WalArchive, &out.WalArchive
+		*out = new(DatabaseWalArchive)
+		**out = **in
+	}
+	if in.Clone != nil {
+		in, out := &in.Clone, &out.Clone
+		*out = new(DatabaseClone)
+		**out = **in
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PgHba != nil {
+		in, out := &in.PgHba, &out.PgHba
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Patroni != nil {
+		in, out := &in.Patroni, &out.Patroni
+		*out = new(DatabasePatroni)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConnectionPooler != nil {
+		in, out := &in.ConnectionPooler, &out.ConnectionPooler
+		*out = new(DatabaseConnectionPooler)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CredentialRotation != nil {
+		in, out := &in.CredentialRotation, &out.CredentialRotation
+		*out = new(DatabaseCredentialRotation)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresSQL.
@@ -419,10 +795,52 @@ func (in *Redis) DeepCopy() *Redis {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisFallback) DeepCopyInto(out *RedisFallback) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]Hosts, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisFallback.
+func (in *RedisFallback) DeepCopy() *RedisFallback {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisFallback)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisMetrics) DeepCopyInto(out *RedisMetrics) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisMetrics.
+func (in *RedisMetrics) DeepCopy() *RedisMetrics {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisMetrics)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisServer) DeepCopyInto(out *RedisServer) {
 	*out = *in
 	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisServer.
@@ -446,6 +864,36 @@ func (in *RedisSpec) DeepCopyInto(out *RedisSpec) {
 	if in.Sentinel != nil {
 		in, out := &in.Sentinel, &out.Sentinel
 		*out = new(Sentinel)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DialTimeout != nil {
+		in, out := &in.DialTimeout, &out.DialTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ReadTimeout != nil {
+		in, out := &in.ReadTimeout, &out.ReadTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.WriteTimeout != nil {
+		in, out := &in.WriteTimeout, &out.WriteTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.PoolTimeout != nil {
+		in, out := &in.PoolTimeout, &out.PoolTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.IdleTimeout != nil {
+		in, out := &in.IdleTimeout, &out.IdleTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.IdleCheckFrequency != nil {
+		in, out := &in.IdleCheckFrequency, &out.IdleCheckFrequency
+		*out = new(metav1.Duration)
 		**out = **in
 	}
 	if in.Hosts != nil {
@@ -453,6 +901,16 @@ func (in *RedisSpec) DeepCopyInto(out *RedisSpec) {
 		*out = make([]Hosts, len(*in))
 		copy(*out, *in)
 	}
+	if in.Fallback != nil {
+		in, out := &in.Fallback, &out.Fallback
+		*out = new(RedisFallback)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = new(RedisMetrics)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisSpec.
@@ -483,6 +941,14 @@ func (in *S3) DeepCopy() *S3 {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Sentinel) DeepCopyInto(out *Sentinel) {
 	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Sentinel.
@@ -528,6 +994,11 @@ func (in *Storage) DeepCopyInto(out *Storage) {
 		*out = new(Oss)
 		**out = **in
 	}
+	if in.FileSystem != nil {
+		in, out := &in.FileSystem, &out.FileSystem
+		*out = new(FileSystem)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Storage.
@@ -540,6 +1011,96 @@ func (in *Storage) DeepCopy() *Storage {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageBucketStatus) DeepCopyInto(out *StorageBucketStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageBucketStatus.
+func (in *StorageBucketStatus) DeepCopy() *StorageBucketStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageBucketStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageBucketUsage) DeepCopyInto(out *StorageBucketUsage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageBucketUsage.
+func (in *StorageBucketUsage) DeepCopy() *StorageBucketUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageBucketUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageMigrationStatus) DeepCopyInto(out *StorageMigrationStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageMigrationStatus.
+func (in *StorageMigrationStatus) DeepCopy() *StorageMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageProbeStatus) DeepCopyInto(out *StorageProbeStatus) {
+	*out = *in
+	in.LastProbeTime.DeepCopyInto(&out.LastProbeTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageProbeStatus.
+func (in *StorageProbeStatus) DeepCopy() *StorageProbeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageProbeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageUsageStatus) DeepCopyInto(out *StorageUsageStatus) {
+	*out = *in
+	if in.Buckets != nil {
+		in, out := &in.Buckets, &out.Buckets
+		*out = make([]StorageBucketUsage, len(*in))
+		copy(*out, *in)
+	}
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageUsageStatus.
+func (in *StorageUsageStatus) DeepCopy() *StorageUsageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageUsageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Swift) DeepCopyInto(out *Swift) {
 	*out = *in
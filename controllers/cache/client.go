@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -16,13 +17,27 @@ const (
 
 	UpdateMessageRedisCluster = "Redis  %s already update."
 
-	MessageRedisDownScaling     = "Redis downscale from %d to %d"
-	MessageRedisUpScaling       = "Redis upscale from %d to %d"
+	MessageRedisDownScaling     = "Redis %s downscale from %d to %d"
+	MessageRedisUpScaling       = "Redis %s upscale from %d to %d"
 	MessageRedisRollingUpgrades = "Redis resource from %s to %s"
 
+	RedisServerComponent   = "server"
+	RedisSentinelComponent = "sentinel"
+
 	RedisSentinelConnPort  = "26379"
 	RedisRedisConnPort     = "6379"
 	RedisSentinelConnGroup = "mymaster"
+
+	DefaultRedisDialTimeout        = 10 * time.Second
+	DefaultRedisReadTimeout        = 30 * time.Second
+	DefaultRedisWriteTimeout       = 30 * time.Second
+	DefaultRedisPoolTimeout        = 30 * time.Second
+	DefaultRedisIdleTimeout        = 5 * time.Minute
+	DefaultRedisIdleCheckFrequency = time.Minute
+
+	DefaultCorePoolSize = 100
+
+	RedisClusterSlots = "16384"
 )
 
 type RedisConnect struct {
@@ -31,22 +46,43 @@ type RedisConnect struct {
 	Port      string
 	Password  string
 	GroupName string
+	Pool      RedisPoolConfig
+}
+
+// RedisPoolConfig is the connection pool settings of redis client
+type RedisPoolConfig struct {
+	// PoolSize is the maximum number of socket connections, 0 means the default of redis client.
+	PoolSize           int
+	DialTimeout        time.Duration
+	ReadTimeout        time.Duration
+	WriteTimeout       time.Duration
+	PoolTimeout        time.Duration
+	IdleTimeout        time.Duration
+	IdleCheckFrequency time.Duration
 }
 
 // NewRedisPool returns redis sentinel client
 func (c *RedisConnect) NewRedisPool() *rediscli.Client {
 
-	return BuildRedisPool(c.Endpoints, c.Port, c.Password, c.GroupName, 0)
+	return BuildRedisPool(c.Endpoints, c.Port, c.Password, c.GroupName, 0, c.Pool)
 }
 
 // NewRedisClient returns redis client
 func (c *RedisConnect) NewRedisClient() *rediscli.Client {
 
-	return BuildRedisClient(c.Endpoints, c.Port, c.Password, 0)
+	return BuildRedisClient(c.Endpoints, c.Port, c.Password, 0, c.Pool)
+}
+
+// newClient returns redis sentinel client or redis client by the schema
+func (c *RedisConnect) newClient() *rediscli.Client {
+	if c.Schema == RedisSentinelSchema {
+		return c.NewRedisPool()
+	}
+	return c.NewRedisClient()
 }
 
 // BuildRedisPool returns redis connection pool client
-func BuildRedisPool(redisSentinelIP []string, redisSentinelPort, redisSentinelPassword, redisGroupName string, redisIndex int) *rediscli.Client {
+func BuildRedisPool(redisSentinelIP []string, redisSentinelPort, redisSentinelPassword, redisGroupName string, redisIndex int, pool RedisPoolConfig) *rediscli.Client {
 
 	sentinelsInfo := GenHostInfo(redisSentinelIP, redisSentinelPort)
 
@@ -55,13 +91,13 @@ func BuildRedisPool(redisSentinelIP []string, redisSentinelPort, redisSentinelPa
 		SentinelAddrs:      sentinelsInfo,
 		Password:           redisSentinelPassword,
 		DB:                 redisIndex,
-		PoolSize:           100,
-		DialTimeout:        10 * time.Second,
-		ReadTimeout:        30 * time.Second,
-		WriteTimeout:       30 * time.Second,
-		PoolTimeout:        30 * time.Second,
-		IdleTimeout:        time.Millisecond,
-		IdleCheckFrequency: time.Millisecond,
+		PoolSize:           pool.PoolSize,
+		DialTimeout:        pool.DialTimeout,
+		ReadTimeout:        pool.ReadTimeout,
+		WriteTimeout:       pool.WriteTimeout,
+		PoolTimeout:        pool.PoolTimeout,
+		IdleTimeout:        pool.IdleTimeout,
+		IdleCheckFrequency: pool.IdleCheckFrequency,
 	}
 
 	client := rediscli.NewFailoverClient(options)
@@ -71,18 +107,101 @@ func BuildRedisPool(redisSentinelIP []string, redisSentinelPort, redisSentinelPa
 }
 
 // BuildRedisClient returns redis connection client
-func BuildRedisClient(host []string, port, password string, index int) *rediscli.Client {
+func BuildRedisClient(host []string, port, password string, index int, pool RedisPoolConfig) *rediscli.Client {
 	hostInfo := GenHostInfo(host, port)
 	options := &rediscli.Options{
-		Addr:     strings.Join(hostInfo[:], ","),
-		Password: password,
-		DB:       index,
+		Addr:               strings.Join(hostInfo[:], ","),
+		Password:           password,
+		DB:                 index,
+		PoolSize:           pool.PoolSize,
+		DialTimeout:        pool.DialTimeout,
+		ReadTimeout:        pool.ReadTimeout,
+		WriteTimeout:       pool.WriteTimeout,
+		PoolTimeout:        pool.PoolTimeout,
+		IdleTimeout:        pool.IdleTimeout,
+		IdleCheckFrequency: pool.IdleCheckFrequency,
 	}
 	client := rediscli.NewClient(options)
 
 	return client
 }
 
+// NewRedisClusterClient returns redis cluster client, the endpoints are the "host:port" of seed nodes
+func (c *RedisConnect) NewRedisClusterClient() *rediscli.ClusterClient {
+
+	return BuildRedisClusterClient(c.Endpoints, c.Password, c.Pool)
+}
+
+// BuildRedisClusterClient returns redis cluster connection client
+func BuildRedisClusterClient(addrs []string, password string, pool RedisPoolConfig) *rediscli.ClusterClient {
+	options := &rediscli.ClusterOptions{
+		Addrs:              addrs,
+		Password:           password,
+		PoolSize:           pool.PoolSize,
+		DialTimeout:        pool.DialTimeout,
+		ReadTimeout:        pool.ReadTimeout,
+		WriteTimeout:       pool.WriteTimeout,
+		PoolTimeout:        pool.PoolTimeout,
+		IdleTimeout:        pool.IdleTimeout,
+		IdleCheckFrequency: pool.IdleCheckFrequency,
+	}
+
+	return rediscli.NewClusterClient(options)
+}
+
+// CheckRedisCluster checks that all the hash slots are served and no cluster node is failing
+func CheckRedisCluster(client *rediscli.ClusterClient) error {
+	info, err := client.ClusterInfo().Result()
+	if err != nil {
+		return err
+	}
+
+	fields := ParseRedisInfo(info)
+	if fields["cluster_state"] != "ok" {
+		return fmt.Errorf("redis cluster state is %q", fields["cluster_state"])
+	}
+	for _, key := range []string{"cluster_slots_assigned", "cluster_slots_ok"} {
+		if fields[key] != RedisClusterSlots {
+			return fmt.Errorf("redis cluster slots are not fully covered, %s is %s", key, fields[key])
+		}
+	}
+
+	nodes, err := client.ClusterNodes().Result()
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(nodes), "\n") {
+		node := strings.Fields(line)
+		if len(node) < 8 {
+			continue
+		}
+		for _, flag := range strings.Split(node[2], ",") {
+			switch flag {
+			case "fail", "fail?", "handshake", "noaddr":
+				return fmt.Errorf("redis cluster node %s is in %s state", node[1], flag)
+			}
+		}
+		if node[7] != "connected" {
+			return fmt.Errorf("redis cluster node %s is %s", node[1], node[7])
+		}
+	}
+
+	return nil
+}
+
+// ParseRedisInfo parses the "key:value" lines returned by redis INFO like commands
+func ParseRedisInfo(info string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	return fields
+}
+
 // GenHostInfo splice host and port
 func GenHostInfo(endpoint []string, port string) []string {
 	var hostInfo []string
@@ -17,6 +17,9 @@ const (
 	ManualFailoverRedisError          = "Manual failover redis error"
 	UpdateRedisCrError                = "Update redis cr error"
 	DefaultUnstructuredConverterError = "Default unstructured converter error"
+	DeployRedisServiceMonitorError    = "Deploy redis service monitor error"
+	CheckRedisClusterError            = "Check redis cluster error"
+	RedisReplicasBelowQuorumError     = "Redis replicas below quorum error"
 )
 
 const (
@@ -12,16 +12,35 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+const (
+	ServiceMonitorCRDName = "servicemonitors.monitoring.coreos.com"
+	RedisMetricsPortName  = "http-metrics"
+)
+
 var (
 	redisFailoversGVR = redisCli.SchemeGroupVersion.WithResource(redisCli.RFNamePlural)
+
+	serviceMonitorsGVR = schema.GroupVersionResource{
+		Group:    "monitoring.coreos.com",
+		Version:  "v1",
+		Resource: "servicemonitors",
+	}
+
+	crdGVR = schema.GroupVersionResource{
+		Group:    "apiextensions.k8s.io",
+		Version:  "v1",
+		Resource: "customresourcedefinitions",
+	}
 )
 
 // generateRedisCR returns RedisFailovers CRs
 func (redis *RedisReconciler) generateRedisCR() (*unstructured.Unstructured, error) {
 	redisResource := redis.GetRedisResource()
+	sentinelResource := redis.GetRedisSentinelResource()
 	redisRep := redis.GetRedisServerReplica()
 	sentinelRep := redis.GetRedisSentinelReplica()
 	storageSize := redis.GetRedisStorageSize()
@@ -38,23 +57,27 @@ func (redis *RedisReconciler) generateRedisCR() (*unstructured.Unstructured, err
 		},
 		Spec: redisCli.RedisFailoverSpec{
 			Redis: redisCli.RedisSettings{
-				Replicas: redisRep,
-				Resources: corev1.ResourceRequirements{
-					Requests: redisResource,
-					Limits:   redisResource,
-				},
+				Replicas:     redisRep,
+				Resources:    redisResource,
+				CustomConfig: redis.GetRedisCustomConfig(),
 			},
 			Sentinel: redisCli.SentinelSettings{
-				Replicas: sentinelRep,
-				Resources: corev1.ResourceRequirements{
-					Requests: redisResource,
-					Limits:   redisResource,
-				},
+				Replicas:     sentinelRep,
+				Resources:    sentinelResource,
+				CustomConfig: redis.GetRedisSentinelCustomConfig(),
 			},
 			Auth: redisCli.AuthSettings{SecretPath: redis.HarborCluster.Name},
 		},
 	}
 
+	if redis.IsMetricsEnabled() {
+		conf.Spec.Redis.Exporter = redisCli.RedisExporter{
+			Enabled: true,
+			Image:   redis.HarborCluster.Spec.Redis.Spec.Metrics.Image,
+		}
+	}
+
+	conf.Spec.Redis.Storage.KeepAfterDeletion = redis.GetRedisKeepAfterDeletion()
 	conf.Spec.Redis.Storage.PersistentVolumeClaim = redis.generateRedisStorage(storageSize, redis.HarborCluster.Name)
 
 	mapResult, err := runtime.DefaultUnstructuredConverter.ToUnstructured(conf)
@@ -94,7 +117,8 @@ func (redis *RedisReconciler) generateRedisStorage(size, name string) *corev1.Pe
 			AccessModes: []corev1.PersistentVolumeAccessMode{
 				corev1.ReadWriteOnce,
 			},
-			Selector: nil,
+			StorageClassName: redis.GetRedisStorageClassName(),
+			Selector:         nil,
 			Resources: corev1.ResourceRequirements{
 				Requests: corev1.ResourceList{
 					"storage": storage,
@@ -149,3 +173,38 @@ func (redis *RedisReconciler) generateService() *corev1.Service {
 		},
 	}
 }
+
+// generateServiceMonitor returns the ServiceMonitor which scrapes the redis exporter
+// through the headless service created by redis operator.
+func (redis *RedisReconciler) generateServiceMonitor() *unstructured.Unstructured {
+	sm := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{
+					"matchLabels": map[string]interface{}{
+						"app.kubernetes.io/component": "redis",
+						"app.kubernetes.io/name":      redis.GetHarborClusterName(),
+						"app.kubernetes.io/part-of":   "redis-failover",
+					},
+				},
+				"namespaceSelector": map[string]interface{}{
+					"matchNames": []interface{}{redis.GetHarborClusterNamespace()},
+				},
+				"endpoints": []interface{}{
+					map[string]interface{}{
+						"port":     RedisMetricsPortName,
+						"interval": redis.GetRedisMetricsInterval(),
+					},
+				},
+			},
+		},
+	}
+
+	sm.SetAPIVersion("monitoring.coreos.com/v1")
+	sm.SetKind("ServiceMonitor")
+	sm.SetName(redis.GetRedisName())
+	sm.SetNamespace(redis.GetHarborClusterNamespace())
+	sm.SetLabels(redis.Labels)
+
+	return sm
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/goharbor/harbor-cluster-operator/lcm"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -56,6 +57,11 @@ func (redis *RedisReconciler) Deploy() (*lcm.CRStatus, error) {
 	}
 
 	redis.Log.Info("Redis has been created.", "namespace", redis.HarborCluster.Namespace, "name", redis.HarborCluster.Name)
+
+	if err := redis.DeployServiceMonitor(); err != nil {
+		return cacheNotReadyStatus(DeployRedisServiceMonitorError, err.Error()), err
+	}
+
 	return cacheUnknownStatus(), nil
 }
 
@@ -95,3 +101,60 @@ func (redis *RedisReconciler) DeployService() error {
 
 	return err
 }
+
+// DeployServiceMonitor deploy the ServiceMonitor of redis exporter if the ServiceMonitor CRD is installed.
+// The ServiceMonitor will be removed if metrics is disabled.
+func (redis *RedisReconciler) DeployServiceMonitor() error {
+	installed, err := redis.IsServiceMonitorInstalled()
+	if err != nil || !installed {
+		return err
+	}
+
+	smClient := redis.DClient.WithResource(serviceMonitorsGVR).WithNamespace(redis.HarborCluster.Namespace)
+	name := redis.GetRedisName()
+
+	actual, err := smClient.Get(name, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if !redis.IsMetricsEnabled() {
+		if err != nil {
+			return nil
+		}
+		redis.Log.Info("Deleting Redis ServiceMonitor", "namespace", redis.HarborCluster.Namespace, "name", name)
+		return smClient.Delete(name, metav1.DeleteOptions{})
+	}
+
+	expect := redis.generateServiceMonitor()
+	if err := controllerutil.SetControllerReference(redis.HarborCluster, expect, redis.Scheme); err != nil {
+		return err
+	}
+
+	if err != nil {
+		redis.Log.Info("Creating Redis ServiceMonitor", "namespace", redis.HarborCluster.Namespace, "name", name)
+		_, err = smClient.Create(expect, metav1.CreateOptions{})
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(actual.Object["spec"], expect.Object["spec"]) {
+		return nil
+	}
+
+	redis.Log.Info("Updating Redis ServiceMonitor", "namespace", redis.HarborCluster.Namespace, "name", name)
+	expect.SetResourceVersion(actual.GetResourceVersion())
+	_, err = smClient.Update(expect, metav1.UpdateOptions{})
+	return err
+}
+
+// IsServiceMonitorInstalled returns whether the ServiceMonitor CRD of prometheus operator is installed
+func (redis *RedisReconciler) IsServiceMonitorInstalled() (bool, error) {
+	_, err := redis.DClient.WithResource(crdGVR).WithNamespace("").Get(ServiceMonitorCRDName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -43,6 +43,10 @@ func (redis *RedisReconciler) Readiness() (*lcm.CRStatus, error) {
 		err    error
 	)
 
+	if redis.IsRedisCluster() {
+		return redis.ClusterReadiness()
+	}
+
 	switch redis.HarborCluster.Spec.Redis.Kind {
 	case goharborv1.ExternalComponent:
 		client, err = redis.GetExternalRedisInfo()
@@ -67,9 +71,65 @@ func (redis *RedisReconciler) Readiness() (*lcm.CRStatus, error) {
 	redis.Log.Info("Redis already ready.",
 		"namespace", redis.HarborCluster.Namespace, "name", redis.HarborCluster.Name)
 
+	return redis.DeployComponentSecrets()
+}
+
+// ClusterReadiness reconcile will check external Redis cluster if that has available.
+// It does:
+// - create redis cluster client
+// - check the slots coverage and the state of cluster nodes
+// - ping the fallback redis of the components which do not support redis cluster
+// - return redis properties of all the components
+func (redis *RedisReconciler) ClusterReadiness() (*lcm.CRStatus, error) {
+	client, err := redis.GetExternalRedisClusterInfo()
+	if err != nil {
+		redis.Log.Error(err, "Fail to create redis cluster client.",
+			"namespace", redis.HarborCluster.Namespace, "name", redis.HarborCluster.Name)
+		return cacheNotReadyStatus(GetRedisClientError, err.Error()), err
+	}
+
+	defer client.Close()
+
+	if err := CheckRedisCluster(client); err != nil {
+		redis.Log.Error(err, "Fail to check Redis cluster.",
+			"namespace", redis.HarborCluster.Namespace, "name", redis.HarborCluster.Name)
+		return cacheNotReadyStatus(CheckRedisClusterError, err.Error()), err
+	}
+
+	fallback, err := redis.GetExternalRedisFallbackInfo()
+	if err != nil {
+		redis.Log.Error(err, "Fail to create fallback redis client.",
+			"namespace", redis.HarborCluster.Namespace, "name", redis.HarborCluster.Name)
+		return cacheNotReadyStatus(GetRedisClientError, err.Error()), err
+	}
+
+	defer fallback.Close()
+
+	if err := fallback.Ping().Err(); err != nil {
+		redis.Log.Error(err, "Fail to check fallback Redis.",
+			"namespace", redis.HarborCluster.Namespace, "name", redis.HarborCluster.Name)
+		return cacheNotReadyStatus(CheckRedisHealthError, err.Error()), err
+	}
+
+	redis.Log.Info("Redis cluster already ready.",
+		"namespace", redis.HarborCluster.Namespace, "name", redis.HarborCluster.Name)
+
+	return redis.DeployComponentSecrets()
+}
+
+// DeployComponentSecrets deploy the redis secrets of harbor components,
+// the components which do not support redis cluster connect to the fallback redis.
+func (redis *RedisReconciler) DeployComponentSecrets() (*lcm.CRStatus, error) {
 	properties := lcm.Properties{}
 	for _, component := range components {
 		url := redis.RedisConnect.GenRedisConnURL(component)
+		if url == "" && redis.FallbackConnect != nil {
+			url = redis.FallbackConnect.GenRedisConnURL(component)
+		}
+		if url == "" {
+			continue
+		}
+
 		secretName := fmt.Sprintf("%s-redis", strings.ToLower(component))
 		propertyName := fmt.Sprintf("%sSecret", component)
 
@@ -111,69 +171,131 @@ func (redis *RedisReconciler) DeployComponentSecret(component, url, namespace, s
 			"name", secretName,
 			"component", component)
 		return redis.Client.Create(sc)
+	} else if err != nil {
+		return err
 	}
 
-	return err
+	if string(secret.Data["url"]) == url && string(secret.Data["namespace"]) == namespace {
+		return nil
+	}
+
+	redis.Log.Info("Updating Harbor Component Secret",
+		"namespace", redis.HarborCluster.Namespace,
+		"name", secretName,
+		"component", component)
+	secret.StringData = sc.StringData
+	return redis.Client.Update(secret)
 }
 
+// GetExternalRedisInfo returns external redis sentinel pool client or redis server client
 func (redis *RedisReconciler) GetExternalRedisInfo() (*rediscli.Client, error) {
-	var (
-		connect  *RedisConnect
-		endpoint []string
-		port     string
-		client   *rediscli.Client
-		err      error
-		pw       string
-	)
-	spec := redis.HarborCluster.Spec.Redis.Spec
-	switch spec.Schema {
-	case RedisSentinelSchema:
-		if len(spec.Hosts) < 1 || spec.GroupName == "" {
-			return nil, errors.New(".redis.spec.hosts or .redis.spec.groupName is invalid")
-		}
+	connect, err := redis.newExternalRedisConnect(redis.HarborCluster.Spec.Redis.Spec, ".redis.spec")
+	if err != nil {
+		return nil, err
+	}
 
-		endpoint, port = GetExternalRedisHost(spec)
+	redis.RedisConnect = connect
+	return connect.newClient(), nil
+}
 
-		if spec.SecretName != "" {
-			pw, err = redis.GetExternalRedisPassword(spec)
-		}
+// GetExternalRedisFallbackInfo returns the client of the fallback redis used along with external redis cluster
+func (redis *RedisReconciler) GetExternalRedisFallbackInfo() (*rediscli.Client, error) {
+	fallback := redis.HarborCluster.Spec.Redis.Spec.Fallback
+	if fallback == nil {
+		return nil, errors.New(".redis.spec.fallback is required by redis cluster schema")
+	}
 
-		connect = &RedisConnect{
-			Endpoints: endpoint,
-			Port:      port,
-			Password:  pw,
-			GroupName: spec.GroupName,
-			Schema:    RedisSentinelSchema,
-		}
+	spec := redis.HarborCluster.Spec.Redis.Spec.DeepCopy()
+	spec.Schema = fallback.Schema
+	spec.Hosts = fallback.Hosts
+	spec.SecretName = fallback.SecretName
+	spec.GroupName = fallback.GroupName
 
-		redis.RedisConnect = connect
-		client = connect.NewRedisPool()
+	connect, err := redis.newExternalRedisConnect(spec, ".redis.spec.fallback")
+	if err != nil {
+		return nil, err
+	}
+
+	redis.FallbackConnect = connect
+	return connect.newClient(), nil
+}
+
+// newExternalRedisConnect returns the connection of external redis sentinel or redis server,
+// path is the field path of spec used in the error messages.
+func (redis *RedisReconciler) newExternalRedisConnect(spec *goharborv1.RedisSpec, path string) (*RedisConnect, error) {
+	switch spec.Schema {
+	case RedisSentinelSchema:
+		if len(spec.Hosts) < 1 || spec.GroupName == "" {
+			return nil, fmt.Errorf("%s.hosts or %s.groupName is invalid", path, path)
+		}
 	case RedisServerSchema:
 		if len(spec.Hosts) != 1 {
-			return nil, errors.New(".redis.spec.hosts is invalid")
+			return nil, fmt.Errorf("%s.hosts is invalid", path)
 		}
-		endpoint, port = GetExternalRedisHost(spec)
+	default:
+		return nil, fmt.Errorf("%s.schema %q is invalid", path, spec.Schema)
+	}
 
-		if spec.SecretName != "" {
-			pw, err = redis.GetExternalRedisPassword(spec)
+	var pw string
+	if spec.SecretName != "" {
+		var err error
+		if pw, err = redis.GetExternalRedisPassword(spec); err != nil {
+			return nil, err
 		}
+	}
 
-		connect = &RedisConnect{
-			Endpoints: endpoint,
-			Port:      port,
-			Password:  pw,
-			GroupName: spec.GroupName,
-			Schema:    RedisServerSchema,
+	endpoint, port := GetExternalRedisHost(spec)
+	return &RedisConnect{
+		Endpoints: endpoint,
+		Port:      port,
+		Password:  pw,
+		GroupName: spec.GroupName,
+		Schema:    spec.Schema,
+		Pool:      redis.GetRedisPoolConfig(),
+	}, nil
+}
+
+// GetExternalRedisClusterInfo returns external redis cluster client
+func (redis *RedisReconciler) GetExternalRedisClusterInfo() (*rediscli.ClusterClient, error) {
+	var (
+		pw  string
+		err error
+	)
+
+	spec := redis.HarborCluster.Spec.Redis.Spec
+	if len(spec.Hosts) < 1 {
+		return nil, errors.New(".redis.spec.hosts is invalid")
+	}
+
+	if spec.SecretName != "" {
+		pw, err = redis.GetExternalRedisPassword(spec)
+		if err != nil {
+			return nil, err
 		}
-		redis.RedisConnect = connect
-		client = connect.NewRedisClient()
 	}
 
-	if err != nil {
-		return nil, err
+	connect := &RedisConnect{
+		Endpoints: GetExternalRedisClusterAddrs(spec),
+		Password:  pw,
+		Schema:    goharborv1.RedisClusterSchema,
+		Pool:      redis.GetRedisPoolConfig(),
 	}
+	redis.RedisConnect = connect
 
-	return client, nil
+	return connect.NewRedisClusterClient(), nil
+}
+
+// GetExternalRedisClusterAddrs returns the "host:port" seed list of external redis cluster
+func GetExternalRedisClusterAddrs(spec *goharborv1.RedisSpec) []string {
+	addrs := make([]string, 0, len(spec.Hosts))
+	for _, host := range spec.Hosts {
+		port := host.Port
+		if port == "" {
+			port = RedisRedisConnPort
+		}
+		addrs = append(addrs, host.Host+":"+port)
+	}
+	return addrs
 }
 
 // GetExternalRedisHost returns external redis host list and port
@@ -242,6 +364,7 @@ func (redis *RedisReconciler) GetInClusterRedisInfo() (*rediscli.Client, error)
 			Port:      RedisSentinelConnPort,
 			Password:  password,
 			GroupName: RedisSentinelConnGroup,
+			Pool:      redis.GetRedisPoolConfig(),
 		}
 		redis.RedisConnect = connect
 		client = connect.NewRedisPool()
@@ -258,6 +381,7 @@ func (redis *RedisReconciler) GetInClusterRedisInfo() (*rediscli.Client, error)
 			Password:  password,
 			GroupName: spec.GroupName,
 			Schema:    RedisServerSchema,
+			Pool:      redis.GetRedisPoolConfig(),
 		}
 		redis.RedisConnect = connect
 		client = connect.NewRedisClient()
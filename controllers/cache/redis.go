@@ -27,6 +27,8 @@ type RedisReconciler struct {
 	ActualCR      *unstructured.Unstructured
 	Labels        map[string]string
 	RedisConnect  *RedisConnect
+	// FallbackConnect is the connection of the components which do not support redis cluster
+	FallbackConnect *RedisConnect
 }
 
 // Reconciler implements the reconcile logic of redis service
@@ -57,9 +59,26 @@ func (redis *RedisReconciler) Reconcile() (*lcm.CRStatus, error) {
 		redis.ActualCR = actualCR
 		redis.ExpectCR = expectCR
 
-		crStatus, err := redis.Update(nil)
+		isScale, err := redis.IsScalingEvent()
 		if err != nil {
-			return crStatus, err
+			return cacheNotReadyStatus(DefaultUnstructuredConverterError, err.Error()), err
+		}
+
+		// the other changes are left to the next reconcile after scaling.
+		if isScale {
+			crStatus, err := redis.Scale()
+			if err != nil {
+				return crStatus, err
+			}
+		} else {
+			crStatus, err := redis.Update(nil)
+			if err != nil {
+				return crStatus, err
+			}
+		}
+
+		if err := redis.DeployServiceMonitor(); err != nil {
+			return cacheNotReadyStatus(DeployRedisServiceMonitorError, err.Error()), err
 		}
 	}
 
@@ -83,10 +102,6 @@ func (redis *RedisReconciler) Delete() (*lcm.CRStatus, error) {
 	panic("implement me")
 }
 
-func (redis *RedisReconciler) Scale() (*lcm.CRStatus, error) {
-	panic("implement me")
-}
-
 func (redis *RedisReconciler) Update(spec *goharborv1.HarborCluster) (*lcm.CRStatus, error) {
 	crStatus, err := redis.RollingUpgrades()
 	if err != nil {
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/lcm"
+	redisCli "github.com/spotahome/redis-operator/api/redisfailover/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// IsScalingEvent returns whether the replicas of redis server or sentinel in spec
+// differ from the RedisFailovers CR.
+func (redis *RedisReconciler) IsScalingEvent() (bool, error) {
+	actualCR, expectCR, err := redis.getRedisFailovers()
+	if err != nil {
+		return false, err
+	}
+
+	return actualCR.Spec.Redis.Replicas != expectCR.Spec.Redis.Replicas ||
+		actualCR.Spec.Sentinel.Replicas != expectCR.Spec.Sentinel.Replicas, nil
+}
+
+// Scale reconcile will scale the Redis sentinel cluster to the replicas in spec.
+// It does:
+// - refuse to scale redis server below the minimum, or to scale sentinel down below the quorum size
+// - scale up redis server and sentinel to the desired replicas
+// - scale down one replica at a time, and fail over first if the removed redis node is master
+// - emit scaling events
+func (redis *RedisReconciler) Scale() (*lcm.CRStatus, error) {
+	actualCR, expectCR, err := redis.getRedisFailovers()
+	if err != nil {
+		return cacheNotReadyStatus(DefaultUnstructuredConverterError, err.Error()), err
+	}
+
+	desiredRedis := expectCR.Spec.Redis.Replicas
+	desiredSentinel := expectCR.Spec.Sentinel.Replicas
+	currentRedis := actualCR.Spec.Redis.Replicas
+	currentSentinel := actualCR.Spec.Sentinel.Replicas
+
+	// the same rule as the webhook, in case the spec is admitted without it.
+	// The existing sentinels below the quorum size are kept, but they are not scaled down any further.
+	if desiredRedis < goharborv1.MinRedisServerReplicas ||
+		(desiredSentinel < currentSentinel && desiredSentinel < goharborv1.MinRedisSentinelReplicas) {
+		err := fmt.Errorf("redis replicas %d and sentinel replicas %d must not be less than %d and %d",
+			desiredRedis, desiredSentinel, goharborv1.MinRedisServerReplicas, goharborv1.MinRedisSentinelReplicas)
+		return cacheNotReadyStatus(RedisReplicasBelowQuorumError, err.Error()), err
+	}
+
+	nextRedis := desiredRedis
+	if desiredRedis < currentRedis {
+		ready, err := redis.isRedisServerStable(currentRedis)
+		if err != nil {
+			return cacheNotReadyStatus(GetRedisServerPodError, err.Error()), err
+		}
+		if !ready {
+			return cacheUnknownStatus(), nil
+		}
+
+		nextRedis = currentRedis - 1
+		removedPod := fmt.Sprintf("%s-%d", redis.GetRedisName(), nextRedis)
+		master, err := redis.isRedisMaster(removedPod)
+		if err != nil {
+			return cacheNotReadyStatus(CheckRedisIsMasterError, err.Error()), err
+		}
+		if master {
+			redis.Log.Info("Redis pod to be removed is master, fail over first.",
+				"namespace", redis.HarborCluster.Namespace, "pod", removedPod)
+			if err := redis.failoverRedis(); err != nil {
+				return cacheNotReadyStatus(ManualFailoverRedisError, err.Error()), err
+			}
+			return cacheUnknownStatus(), nil
+		}
+	}
+
+	nextSentinel := desiredSentinel
+	if desiredSentinel < currentSentinel {
+		nextSentinel = currentSentinel - 1
+	}
+
+	actualCR.Spec.Redis.Replicas = nextRedis
+	actualCR.Spec.Sentinel.Replicas = nextSentinel
+
+	redis.Log.Info("Scale Redis.",
+		"namespace", redis.HarborCluster.Namespace, "name", redis.HarborCluster.Name,
+		"redis", nextRedis, "sentinel", nextSentinel)
+
+	crdClient := redis.DClient.WithResource(redisFailoversGVR).WithNamespace(redis.HarborCluster.Namespace)
+	if err := Update(crdClient, *actualCR, *actualCR); err != nil {
+		return cacheNotReadyStatus(UpdateRedisCrError, err.Error()), err
+	}
+
+	redis.recordScaleEvent(RedisServerComponent, currentRedis, nextRedis)
+	redis.recordScaleEvent(RedisSentinelComponent, currentSentinel, nextSentinel)
+
+	return cacheUnknownStatus(), nil
+}
+
+// recordScaleEvent emits the scale event of redis server or sentinel if replicas is changed
+func (redis *RedisReconciler) recordScaleEvent(component string, current, next int32) {
+	switch {
+	case next > current:
+		msg := fmt.Sprintf(MessageRedisUpScaling, component, current, next)
+		redis.Recorder.Event(redis.HarborCluster, corev1.EventTypeNormal, RedisUpScaling, msg)
+	case next < current:
+		msg := fmt.Sprintf(MessageRedisDownScaling, component, current, next)
+		redis.Recorder.Event(redis.HarborCluster, corev1.EventTypeNormal, RedisDownScaling, msg)
+	}
+}
+
+// getRedisFailovers returns the actual and expect RedisFailovers CR
+func (redis *RedisReconciler) getRedisFailovers() (*redisCli.RedisFailover, *redisCli.RedisFailover, error) {
+	var actualCR redisCli.RedisFailover
+	var expectCR redisCli.RedisFailover
+
+	if err := runtime.DefaultUnstructuredConverter.
+		FromUnstructured(redis.ActualCR.UnstructuredContent(), &actualCR); err != nil {
+		return nil, nil, err
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.
+		FromUnstructured(redis.ExpectCR.UnstructuredContent(), &expectCR); err != nil {
+		return nil, nil, err
+	}
+
+	return &actualCR, &expectCR, nil
+}
+
+// isRedisServerStable returns whether all the redis server pods are ready,
+// so that the next replica can be removed.
+func (redis *RedisReconciler) isRedisServerStable(replicas int32) (bool, error) {
+	sts, _, err := redis.GetStatefulSetPods()
+	if err != nil {
+		return false, err
+	}
+
+	return sts.Status.ReadyReplicas == replicas && sts.Status.Replicas == replicas, nil
+}
+
+// isRedisMaster returns whether the redis server pod is master
+func (redis *RedisReconciler) isRedisMaster(podName string) (bool, error) {
+	pod := &corev1.Pod{}
+	err := redis.Client.Get(types.NamespacedName{Name: podName, Namespace: redis.HarborCluster.Namespace}, pod)
+	if err != nil {
+		return false, err
+	}
+
+	password, err := redis.GetRedisPassword(redis.HarborCluster.Name)
+	if err != nil {
+		return false, err
+	}
+
+	client := BuildRedisClient([]string{pod.Status.PodIP}, RedisRedisConnPort, password, 0, redis.GetRedisPoolConfig())
+	defer client.Close()
+
+	info, err := client.Info("replication").Result()
+	if err != nil {
+		return false, err
+	}
+
+	return strings.EqualFold(ParseRedisInfo(info)["role"], "master"), nil
+}
+
+// failoverRedis asks sentinel to fail over the master to a replica
+func (redis *RedisReconciler) failoverRedis() error {
+	_, sentinelPodList, err := redis.GetDeploymentPods()
+	if err != nil {
+		return err
+	}
+
+	_, currentSentinelPods := redis.GetPodsStatus(sentinelPodList.Items)
+	if len(currentSentinelPods) == 0 {
+		return fmt.Errorf("no running sentinel pod")
+	}
+
+	endpoint := redis.GetSentinelServiceUrl(currentSentinelPods)
+	client := BuildRedisClient([]string{endpoint}, RedisSentinelConnPort, "", 0, redis.GetRedisPoolConfig())
+	defer client.Close()
+
+	return client.Do("SENTINEL", "FAILOVER", RedisSentinelConnGroup).Err()
+}
@@ -38,9 +38,13 @@ func (redis *RedisReconciler) RollingUpgrades() (*lcm.CRStatus, error) {
 		return cacheNotReadyStatus(DefaultUnstructuredConverterError, err.Error()), err
 	}
 
+	// replicas are left to the scaling phase.
+	expectCR.Spec.Redis.Replicas = actualCR.Spec.Redis.Replicas
+	expectCR.Spec.Sentinel.Replicas = actualCR.Spec.Sentinel.Replicas
+
 	if !IsEqual(expectCR, actualCR) {
 		msg := fmt.Sprintf(UpdateMessageRedisCluster, redis.HarborCluster.Name)
-		redis.Recorder.Event(redis.HarborCluster, corev1.EventTypeNormal, RedisUpScaling, msg)
+		redis.Recorder.Event(redis.HarborCluster, corev1.EventTypeNormal, RedisRollingUpgrades, msg)
 
 		redis.Log.Info(
 			"Update Redis resource",
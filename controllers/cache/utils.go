@@ -3,6 +3,9 @@ package cache
 import (
 	"fmt"
 	"math/rand"
+	"sort"
+	"strings"
+	"time"
 
 	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
 	"github.com/goharbor/harbor-cluster-operator/lcm"
@@ -10,12 +13,24 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	labels1 "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+var (
+	// redisClusterComponentNames maps the redis components to the harbor component names used by validation
+	redisClusterComponentNames = map[string]string{
+		HarborChartMuseum: "chartmuseum",
+		HarborClair:       "clair",
+		HarborJobService:  "jobservice",
+		HarborRegistry:    "registry",
+		HarborCore:        "core",
+	}
+)
+
 const (
 	ReidsType    = "rfr"
 	SentinelType = "rfs"
@@ -132,23 +147,51 @@ func (redis *RedisReconciler) GetHarborClusterNamespace() string {
 }
 
 // GetRedisResource returns redis resource
-func (redis *RedisReconciler) GetRedisResource() corev1.ResourceList {
-	resources := corev1.ResourceList{}
-
+func (redis *RedisReconciler) GetRedisResource() corev1.ResourceRequirements {
 	if redis.HarborCluster.Spec.Redis.Spec.Server == nil {
-		return GenerateResourceList("1", "2Gi")
+		resources := GenerateResourceList("1", "2Gi")
+		return corev1.ResourceRequirements{
+			Requests: resources,
+			Limits:   resources,
+		}
 	}
 
-	cpu := redis.HarborCluster.Spec.Redis.Spec.Server.Resources.Requests.Cpu()
-	mem := redis.HarborCluster.Spec.Redis.Spec.Server.Resources.Requests.Memory()
+	return GenerateResourceRequirements(redis.HarborCluster.Spec.Redis.Spec.Server.Resources)
+}
 
-	if cpu != nil {
-		resources[corev1.ResourceCPU] = *cpu
+// GetRedisSentinelResource returns redis sentinel resource,
+// the resource of redis server will be used if sentinel resource is not set.
+func (redis *RedisReconciler) GetRedisSentinelResource() corev1.ResourceRequirements {
+	sentinel := redis.HarborCluster.Spec.Redis.Spec.Sentinel
+	if sentinel == nil || len(sentinel.Resources.Requests) == 0 {
+		return redis.GetRedisResource()
 	}
-	if mem != nil {
-		resources[corev1.ResourceMemory] = *mem
+
+	return GenerateResourceRequirements(sentinel.Resources)
+}
+
+// GenerateResourceRequirements returns resource requirements with cpu and memory,
+// limits are the same as requests if limits is not set.
+func GenerateResourceRequirements(spec corev1.ResourceRequirements) corev1.ResourceRequirements {
+	requests := corev1.ResourceList{}
+	requests[corev1.ResourceCPU] = *spec.Requests.Cpu()
+	requests[corev1.ResourceMemory] = *spec.Requests.Memory()
+
+	if len(spec.Limits) == 0 {
+		return corev1.ResourceRequirements{
+			Requests: requests,
+			Limits:   requests,
+		}
+	}
+
+	limits := corev1.ResourceList{}
+	limits[corev1.ResourceCPU] = *spec.Limits.Cpu()
+	limits[corev1.ResourceMemory] = *spec.Limits.Memory()
+
+	return corev1.ResourceRequirements{
+		Requests: requests,
+		Limits:   limits,
 	}
-	return resources
 }
 
 // GenerateResourceList returns resource list
@@ -200,6 +243,109 @@ func (redis *RedisReconciler) GetRedisStorageSize() string {
 	return redis.HarborCluster.Spec.Redis.Spec.Server.Storage
 }
 
+// GetRedisStorageClassName returns redis server storage class name
+func (redis *RedisReconciler) GetRedisStorageClassName() *string {
+	if redis.HarborCluster.Spec.Redis.Spec.Server == nil {
+		return nil
+	}
+
+	if redis.HarborCluster.Spec.Redis.Spec.Server.StorageClassName == "" {
+		return nil
+	}
+	return &redis.HarborCluster.Spec.Redis.Spec.Server.StorageClassName
+}
+
+// GetRedisKeepAfterDeletion returns whether redis server storage should be kept after deletion
+func (redis *RedisReconciler) GetRedisKeepAfterDeletion() bool {
+	if redis.HarborCluster.Spec.Redis.Spec.Server == nil {
+		return false
+	}
+	return redis.HarborCluster.Spec.Redis.Spec.Server.KeepAfterDeletion
+}
+
+// GetRedisCustomConfig returns redis server config directives
+func (redis *RedisReconciler) GetRedisCustomConfig() []string {
+	if redis.HarborCluster.Spec.Redis.Spec.Server == nil {
+		return nil
+	}
+	return GenerateCustomConfig(redis.HarborCluster.Spec.Redis.Spec.Server.Config)
+}
+
+// GetRedisSentinelCustomConfig returns redis sentinel config directives
+func (redis *RedisReconciler) GetRedisSentinelCustomConfig() []string {
+	if redis.HarborCluster.Spec.Redis.Spec.Sentinel == nil {
+		return nil
+	}
+	return GenerateCustomConfig(redis.HarborCluster.Spec.Redis.Spec.Sentinel.Config)
+}
+
+// GetRedisPoolConfig returns the connection pool settings of redis client,
+// defaults are used for the settings not set in spec.
+func (redis *RedisReconciler) GetRedisPoolConfig() RedisPoolConfig {
+	spec := redis.HarborCluster.Spec.Redis.Spec
+	if spec == nil {
+		spec = &goharborv1.RedisSpec{}
+	}
+
+	return RedisPoolConfig{
+		PoolSize:           spec.PoolSize,
+		DialTimeout:        durationOrDefault(spec.DialTimeout, DefaultRedisDialTimeout),
+		ReadTimeout:        durationOrDefault(spec.ReadTimeout, DefaultRedisReadTimeout),
+		WriteTimeout:       durationOrDefault(spec.WriteTimeout, DefaultRedisWriteTimeout),
+		PoolTimeout:        durationOrDefault(spec.PoolTimeout, DefaultRedisPoolTimeout),
+		IdleTimeout:        durationOrDefault(spec.IdleTimeout, DefaultRedisIdleTimeout),
+		IdleCheckFrequency: durationOrDefault(spec.IdleCheckFrequency, DefaultRedisIdleCheckFrequency),
+	}
+}
+
+func durationOrDefault(d *metav1.Duration, def time.Duration) time.Duration {
+	if d == nil || d.Duration <= 0 {
+		return def
+	}
+	return d.Duration
+}
+
+// IsRedisCluster returns whether the external redis is a redis cluster
+func (redis *RedisReconciler) IsRedisCluster() bool {
+	return redis.HarborCluster.Spec.Redis.Kind == goharborv1.ExternalComponent &&
+		redis.HarborCluster.Spec.Redis.Spec != nil &&
+		redis.HarborCluster.Spec.Redis.Spec.Schema == goharborv1.RedisClusterSchema
+}
+
+// IsMetricsEnabled returns whether the redis exporter is enabled
+func (redis *RedisReconciler) IsMetricsEnabled() bool {
+	metrics := redis.HarborCluster.Spec.Redis.Spec.Metrics
+	return metrics != nil && metrics.Enabled
+}
+
+// GetRedisMetricsInterval returns the scrape interval of redis metrics
+func (redis *RedisReconciler) GetRedisMetricsInterval() string {
+	if !redis.IsMetricsEnabled() || redis.HarborCluster.Spec.Redis.Spec.Metrics.Interval == "" {
+		return "30s"
+	}
+	return redis.HarborCluster.Spec.Redis.Spec.Metrics.Interval
+}
+
+// GenerateCustomConfig returns config directives in "name value" format.
+// The directives are sorted by name, so that the generated CR is stable between reconciles.
+func GenerateCustomConfig(config map[string]string) []string {
+	if len(config) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	directives := make([]string, 0, len(names))
+	for _, name := range names {
+		directives = append(directives, fmt.Sprintf("%s %s", name, config[name]))
+	}
+	return directives
+}
+
 // GetPodsStatus returns deleting  and current pod list
 func (redis *RedisReconciler) GetPodsStatus(podArray []corev1.Pod) ([]corev1.Pod, []corev1.Pod) {
 	deletingPods := make([]corev1.Pod, 0)
@@ -230,6 +376,8 @@ func (c *RedisConnect) GenRedisConnURL(component string) string {
 		return c.genRedisSentinelConnURL(component)
 	case RedisServerSchema:
 		return c.genRedisServerConnURL(component)
+	case goharborv1.RedisClusterSchema:
+		return c.genRedisClusterConnURL(component)
 	default:
 		return ""
 	}
@@ -250,7 +398,7 @@ func (c *RedisConnect) genRedisSentinelConnURL(component string) string {
 func (c *RedisConnect) genRedisServerConnURL(component string) string {
 	hostInfo := GenHostInfo(c.Endpoints, c.Port)
 	if component == HarborCore {
-		return fmt.Sprintf("%s,100,%s", hostInfo[0], c.Password)
+		return fmt.Sprintf("%s,%d,%s", hostInfo[0], c.GetCorePoolSize(), c.Password)
 	}
 	if c.Password != "" {
 		return fmt.Sprintf("redis://:%s@%s/0", c.Password, hostInfo[0])
@@ -259,6 +407,29 @@ func (c *RedisConnect) genRedisServerConnURL(component string) string {
 	return fmt.Sprintf("redis://%s/0", hostInfo[0])
 }
 
+// genRedisClusterConnURL returns redis cluster connection url,
+// empty url is returned if the component does not support redis cluster.
+func (c *RedisConnect) genRedisClusterConnURL(component string) string {
+	if !goharborv1.RedisClusterSupportedComponents[redisClusterComponentNames[component]] {
+		return ""
+	}
+
+	addrs := strings.Join(c.Endpoints, ",")
+	if c.Password != "" {
+		return fmt.Sprintf("redis+cluster://:%s@%s", c.Password, addrs)
+	}
+
+	return fmt.Sprintf("redis+cluster://%s", addrs)
+}
+
+// GetCorePoolSize returns the pool size of harbor core redis session provider
+func (c *RedisConnect) GetCorePoolSize() int {
+	if c.Pool.PoolSize <= 0 {
+		return DefaultCorePoolSize
+	}
+	return c.Pool.PoolSize
+}
+
 // GetRedisFailover returns RedisFailover object
 func (redis *RedisReconciler) GetRedisFailover() (*redisCli.RedisFailover, error) {
 	rf := &redisCli.RedisFailover{}
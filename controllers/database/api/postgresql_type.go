@@ -47,10 +47,10 @@ type PostgresSpec struct {
 	// load balancers' source ranges are the same for master and replica services
 	AllowedSourceRanges []string `json:"allowedSourceRanges"`
 
-	NumberOfInstances  int32                `json:"numberOfInstances"`
-	Users              map[string]UserFlags `json:"users"`
-	MaintenanceWindows []MaintenanceWindow  `json:"maintenanceWindows,omitempty"`
-	//Clone                 CloneDescription            `json:"clone"`
+	NumberOfInstances     int32                       `json:"numberOfInstances"`
+	Users                 map[string]UserFlags        `json:"users"`
+	MaintenanceWindows    []MaintenanceWindow         `json:"maintenanceWindows,omitempty"`
+	Clone                 *CloneDescription           `json:"clone,omitempty"`
 	ClusterName           string                      `json:"-"`
 	Databases             map[string]string           `json:"databases,omitempty"`
 	PreparedDatabases     map[string]PreparedDatabase `json:"preparedDatabases,omitempty"`
@@ -66,6 +66,7 @@ type PostgresSpec struct {
 	//ServiceAnnotations    map[string]string           `json:"serviceAnnotations"`
 	//TLS                   *TLSDescription             `json:"tls"`
 	AdditionalVolumes []AdditionalVolume `json:"additionalVolumes,omitempty"`
+	Env               []v1.EnvVar        `json:"env,omitempty"`
 
 	// deprecated json tags
 	InitContainersOld       []v1.Container `json:"init_containers,omitempty"`
@@ -118,8 +119,8 @@ type AdditionalVolume struct {
 
 // PostgresqlParam describes PostgreSQL version and pairs of configuration parameter name - values.
 type PostgresqlParam struct {
-	PgVersion string `json:"version"`
-	//Parameters map[string]string `json:"parameters"`
+	PgVersion  string            `json:"version"`
+	Parameters map[string]string `json:"parameters,omitempty"`
 }
 
 // ResourceDescription describes CPU and memory resources defined for a cluster.
@@ -136,18 +137,18 @@ type Resources struct {
 
 // Patroni contains Patroni-specific configuration
 type Patroni struct {
-	InitDB map[string]string `json:"initdb"`
-	PgHba  []string          `json:"pg_hba"`
-	//TTL                   uint32                       `json:"ttl"`
-	//LoopWait              uint32                       `json:"loop_wait"`
-	//RetryTimeout          uint32                       `json:"retry_timeout"`
-	//MaximumLagOnFailover  float32                      `json:"maximum_lag_on_failover"` // float32 because https://github.com/kubernetes/kubernetes/issues/30213
+	InitDB               map[string]string `json:"initdb"`
+	PgHba                []string          `json:"pg_hba"`
+	TTL                  uint32            `json:"ttl,omitempty"`
+	LoopWait             uint32            `json:"loop_wait,omitempty"`
+	RetryTimeout         uint32            `json:"retry_timeout,omitempty"`
+	MaximumLagOnFailover float32           `json:"maximum_lag_on_failover,omitempty"` // float32 because https://github.com/kubernetes/kubernetes/issues/30213
 	//Slots                 map[string]map[string]string `json:"slots"`
 	//SynchronousMode       bool                         `json:"synchronous_mode"`
 	//SynchronousModeStrict bool                         `json:"synchronous_mode_strict"`
 }
 
-//StandbyCluster
+// StandbyCluster
 type StandbyDescription struct {
 	S3WalPath string `json:"s3_wal_path,omitempty"`
 }
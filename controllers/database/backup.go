@@ -0,0 +1,619 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/controllers/k8s"
+	"github.com/goharbor/harbor-cluster-operator/lcm"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels1 "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	BackupTargetStorage = "storage"
+	BackupTargetPVC     = "pvc"
+
+	DefaultBackupRetention     = 7
+	DefaultBackupPrefix        = "database-backup"
+	DefaultBackupUploaderImage = "minio/mc:RELEASE.2020-08-08T02-33-58Z"
+
+	BackupDatabaseLabel = "goharbor.io/database-backup"
+	// BackupAllDatabases is the value of BackupDatabaseLabel of the backup CronJob, which backs up all harbor databases
+	BackupAllDatabases = "all"
+	// BackupDatabasesAnnotation lists the databases backed up by the backup Job
+	BackupDatabasesAnnotation = "goharbor.io/database-backup-databases"
+	BackupStorageAlias        = "target"
+	BackupVolumeName          = "backup"
+	BackupMountPath           = "/backup"
+	BackupFileSuffix          = ".dump"
+
+	// DatabaseSSLVolumeName is the volume of the database certificates mounted into backup and restore Jobs,
+	// libpq requires the private key not to be readable by others.
+	DatabaseSSLVolumeName       = "database-ssl"
+	DatabaseSSLMountPath        = "/etc/database-ssl"
+	DatabaseSSLFileMode   int32 = 0400
+
+	backupJobsHistoryLimit int32 = 3
+	backupJobBackoffLimit  int32 = 2
+)
+
+// BackupReconciler reconciles the scheduled logical backups of harbor databases.
+type BackupReconciler struct {
+	PostgreSQLReconciler
+}
+
+// Reconcile reconcile will schedule the backups of harbor databases.
+// It does:
+// - create or update the backup CronJob of all harbor databases
+// - record the last successful backup of each database in status
+// - return not ready status if the last backup of any database is failed
+// It returns nil status if backup is not configured.
+func (backup *BackupReconciler) Reconcile() (*lcm.CRStatus, error) {
+	backup.Client.WithContext(backup.Ctx)
+
+	spec := backup.HarborCluster.Spec.Database.Spec
+	if spec == nil || spec.Backup == nil {
+		backup.HarborCluster.Status.DatabaseBackups = nil
+		return nil, backup.deleteBackupCronJobs("")
+	}
+
+	var storageSecret string
+	if spec.Backup.Target.Kind == BackupTargetStorage {
+		secretName, err := backup.getStorageSecretName()
+		if err != nil {
+			return databaseBackupNotReadyStatus(GetBackupStorageError, err.Error()), nil
+		}
+		if storageSecret, err = backup.DeployBackupStorageSecret(secretName); err != nil {
+			return databaseBackupNotReadyStatus(CreateBackupStorageSecretError, err.Error()), err
+		}
+	}
+
+	databases := backup.GetBackupDatabases()
+	cronJob := backup.generateBackupCronJob(databases, storageSecret)
+	if err := backup.DeployBackupCronJob(cronJob); err != nil {
+		return databaseBackupNotReadyStatus(CreateBackupCronJobError, err.Error()), err
+	}
+
+	if err := backup.deleteBackupCronJobs(cronJob.Name); err != nil {
+		return databaseBackupNotReadyStatus(CreateBackupCronJobError, err.Error()), err
+	}
+
+	return backup.BackupStatus(databases)
+}
+
+// GetBackupDatabases returns the harbor databases and the component secrets to connect them
+func (backup *BackupReconciler) GetBackupDatabases() map[string]string {
+	databases := map[string]string{}
+	for component, database := range componentDatabases {
+		if _, ok := backup.GetDatabases()[database]; !ok {
+			continue
+		}
+		databases[database] = getUserSecretName(component, backup.getActiveUser(component))
+	}
+	return databases
+}
+
+// DeployBackupCronJob creates the backup CronJob or updates it if the spec is changed
+func (backup *BackupReconciler) DeployBackupCronJob(cronJob *batchv1beta1.CronJob) error {
+	if err := controllerutil.SetControllerReference(backup.HarborCluster, cronJob, backup.Scheme); err != nil {
+		return err
+	}
+
+	actual := &batchv1beta1.CronJob{}
+	err := backup.Client.Get(types.NamespacedName{Name: cronJob.Name, Namespace: cronJob.Namespace}, actual)
+	if kerr.IsNotFound(err) {
+		backup.Log.Info("Creating database backup CronJob.",
+			"namespace", cronJob.Namespace, "name", cronJob.Name)
+		return backup.Client.Create(cronJob)
+	} else if err != nil {
+		return err
+	}
+
+	if actual.Spec.Schedule == cronJob.Spec.Schedule && isSuspended(actual) == isSuspended(cronJob) &&
+		equality.Semantic.DeepDerivative(cronJob.Spec.JobTemplate, actual.Spec.JobTemplate) {
+		return nil
+	}
+
+	backup.Log.Info("Updating database backup CronJob.",
+		"namespace", cronJob.Namespace, "name", cronJob.Name)
+	actual.Spec = cronJob.Spec
+	return backup.Client.Update(actual)
+}
+
+// deleteBackupCronJobs deletes the backup CronJobs except the given one,
+// e.g. the CronJobs of each database created by the previous versions.
+func (backup *BackupReconciler) deleteBackupCronJobs(keep string) error {
+	cronJobs := &batchv1beta1.CronJobList{}
+	if err := backup.Client.List(backup.getBackupListOptions(), cronJobs); err != nil {
+		return err
+	}
+
+	for i := range cronJobs.Items {
+		cronJob := &cronJobs.Items[i]
+		if cronJob.Name == keep {
+			continue
+		}
+		backup.Log.Info("Deleting database backup CronJob.",
+			"namespace", cronJob.Namespace, "name", cronJob.Name)
+		if err := backup.Client.Delete(cronJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !kerr.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackupStatus records the last successful backup of each database from the finished backup Jobs
+func (backup *BackupReconciler) BackupStatus(databases map[string]string) (*lcm.CRStatus, error) {
+	jobs := &batchv1.JobList{}
+	if err := backup.Client.List(backup.getBackupListOptions(), jobs); err != nil {
+		return databaseBackupNotReadyStatus(GetBackupJobError, err.Error()), err
+	}
+
+	names := make([]string, 0, len(databases))
+	for database := range databases {
+		names = append(names, database)
+	}
+	sort.Strings(names)
+
+	var (
+		statuses []goharborv1.DatabaseBackupStatus
+		failures []string
+		pending  []string
+	)
+	for _, database := range names {
+		status := goharborv1.DatabaseBackupStatus{Database: database}
+
+		var lastFailed *metav1.Time
+		for i := range jobs.Items {
+			job := &jobs.Items[i]
+			if !isDatabaseBackedUp(job, database) {
+				continue
+			}
+			if t := getJobFinishedTime(job, batchv1.JobComplete); t != nil {
+				if status.LastSuccessfulTime == nil || status.LastSuccessfulTime.Before(t) {
+					status.LastSuccessfulTime = t
+					status.Location = backup.GetBackupLocation(database, job.Name)
+				}
+			}
+			if t := getJobFinishedTime(job, batchv1.JobFailed); t != nil {
+				if lastFailed == nil || lastFailed.Before(t) {
+					lastFailed = t
+				}
+			}
+		}
+
+		// keep the last successful backup recorded before the Jobs are cleaned up.
+		if status.LastSuccessfulTime == nil {
+			for _, recorded := range backup.HarborCluster.Status.DatabaseBackups {
+				if recorded.Database == database {
+					status = recorded
+				}
+			}
+		}
+
+		if lastFailed != nil && (status.LastSuccessfulTime == nil || status.LastSuccessfulTime.Before(lastFailed)) {
+			failures = append(failures, database)
+		} else if status.LastSuccessfulTime == nil {
+			pending = append(pending, database)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	backup.HarborCluster.Status.DatabaseBackups = statuses
+
+	if len(failures) > 0 {
+		return databaseBackupNotReadyStatus(DatabaseBackupFailedError,
+			fmt.Sprintf("the last backup of databases %s failed", strings.Join(failures, ", "))), nil
+	}
+
+	if len(pending) > 0 {
+		return lcm.New(goharborv1.DatabaseBackupReady).
+			WithStatus(corev1.ConditionUnknown).
+			WithReason("waiting for backup").
+			WithMessage(fmt.Sprintf("databases %s have not been backed up yet", strings.Join(pending, ", "))), nil
+	}
+
+	return lcm.New(goharborv1.DatabaseBackupReady).
+		WithStatus(corev1.ConditionTrue).
+		WithReason("database backup succeeded").
+		WithMessage("the last backups of harbor databases are successful."), nil
+}
+
+// GetBackupLocation returns the location of backup file written by the backup Job
+func (backup *BackupReconciler) GetBackupLocation(database, jobName string) string {
+	target := backup.HarborCluster.Spec.Database.Spec.Backup.Target
+	path := fmt.Sprintf("%s/%s/%s%s", backup.getBackupPrefix(), database, jobName, BackupFileSuffix)
+
+	if target.Kind == BackupTargetPVC {
+		return fmt.Sprintf("pvc://%s/%s", target.ClaimName, path)
+	}
+
+	bucket := ""
+	if p := backup.getStorageProperty(); p != nil {
+		if secret, err := backup.getStorageConfig(p.ToString()); err == nil {
+			bucket = secret["bucket"]
+		}
+	}
+	return fmt.Sprintf("s3://%s/%s", bucket, path)
+}
+
+// isRestoring returns whether a restore is in progress, the backups are suspended during the restore,
+// otherwise the half restored databases would be dumped and the backups being restored may be pruned.
+func (backup *BackupReconciler) isRestoring() bool {
+	status := backup.HarborCluster.Status.DatabaseRestore
+	return status != nil && status.Phase != RestorePhaseCompleted && status.Phase != RestorePhaseFailed
+}
+
+func isSuspended(cronJob *batchv1beta1.CronJob) bool {
+	return cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+}
+
+// isDatabaseBackedUp returns whether the database is backed up by the Job,
+// the Jobs created by the previous versions back up the database in their label only.
+func isDatabaseBackedUp(job *batchv1.Job, database string) bool {
+	databases, ok := job.Annotations[BackupDatabasesAnnotation]
+	if !ok {
+		return job.Labels[BackupDatabaseLabel] == database
+	}
+	for _, name := range strings.Split(databases, ",") {
+		if name == database {
+			return true
+		}
+	}
+	return false
+}
+
+func getJobFinishedTime(job *batchv1.Job, conditionType batchv1.JobConditionType) *metav1.Time {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type != conditionType || condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		if conditionType == batchv1.JobComplete && job.Status.CompletionTime != nil {
+			return job.Status.CompletionTime
+		}
+		t := condition.LastTransitionTime
+		return &t
+	}
+	return nil
+}
+
+// DeployBackupStorageSecret deploys the secret which the backup Jobs use to upload backups to storage,
+// it returns the name of the secret.
+func (backup *BackupReconciler) DeployBackupStorageSecret(storageSecretName string) (string, error) {
+	config, err := backup.getStorageConfig(storageSecretName)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := config["regionendpoint"]
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config["region"])
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	u.User = url.UserPassword(config["accesskey"], config["secretkey"])
+
+	name := fmt.Sprintf("%s-database-backup", backup.HarborCluster.Name)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: backup.HarborCluster.Namespace,
+			Labels:    backup.getBackupLabels(""),
+		},
+		StringData: map[string]string{
+			"MC_HOST_" + BackupStorageAlias: u.String(),
+			"BUCKET":                        config["bucket"],
+			"MC_INSECURE":                   config["skipverify"],
+		},
+	}
+	if err := controllerutil.SetControllerReference(backup.HarborCluster, secret, backup.Scheme); err != nil {
+		return "", err
+	}
+
+	actual := &corev1.Secret{}
+	err = backup.Client.Get(types.NamespacedName{Name: name, Namespace: backup.HarborCluster.Namespace}, actual)
+	if kerr.IsNotFound(err) {
+		return name, backup.Client.Create(secret)
+	} else if err != nil {
+		return "", err
+	}
+
+	if isSecretDataEqual(actual.Data, secret.StringData) {
+		return name, nil
+	}
+	actual.Data = nil
+	actual.StringData = secret.StringData
+	return name, backup.Client.Update(actual)
+}
+
+// generateBackupCronJob returns the CronJob backing up all harbor databases in a single Job,
+// so that the backups do not run concurrently on the same volume.
+func (backup *BackupReconciler) generateBackupCronJob(databases map[string]string, storageSecret string) *batchv1beta1.CronJob {
+	spec := backup.HarborCluster.Spec.Database.Spec.Backup
+	labels := backup.getBackupLabels(BackupAllDatabases)
+	historyLimit := backupJobsHistoryLimit
+	backoffLimit := backupJobBackoffLimit
+
+	names := make([]string, 0, len(databases))
+	for database := range databases {
+		names = append(names, database)
+	}
+	sort.Strings(names)
+
+	podSpec := backup.generateBackupPodSpec(names, databases, storageSecret)
+	suspend := backup.isRestoring()
+
+	return &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-database-backup", backup.HarborCluster.Name),
+			Namespace: backup.HarborCluster.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:                   spec.Schedule,
+			Suspend:                    &suspend,
+			ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &historyLimit,
+			FailedJobsHistoryLimit:     &historyLimit,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+					Annotations: map[string]string{
+						BackupDatabasesAnnotation: strings.Join(names, ","),
+					},
+				},
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: labels,
+						},
+						Spec: podSpec,
+					},
+				},
+			},
+		},
+	}
+}
+
+// generateBackupPodSpec returns the pod of backup Job. The databases are dumped one by one by the init containers,
+// the old backups are pruned once all the dumps are succeeded.
+// The backup file is named after the Job, so that the location can be found from the finished Jobs.
+func (backup *BackupReconciler) generateBackupPodSpec(names []string, databases map[string]string, storageSecret string) corev1.PodSpec {
+	target := backup.HarborCluster.Spec.Database.Spec.Backup.Target
+	prune := fmt.Sprintf("sort -r | tail -n +%d", backup.getBackupRetention()+1)
+
+	dir := BackupMountPath
+	if target.Kind == BackupTargetPVC {
+		dir = fmt.Sprintf("%s/%s", BackupMountPath, backup.getBackupPrefix())
+	}
+
+	pod := corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+	}
+
+	for _, database := range names {
+		pod.InitContainers = append(pod.InitContainers, corev1.Container{
+			Name:    fmt.Sprintf("pg-dump-%s", database),
+			Image:   backup.getBackupImage(),
+			Command: []string{"/bin/sh", "-c"},
+			Args: []string{fmt.Sprintf(`set -e
+mkdir -p %[1]s/%[2]s
+pg_dump -Fc -f "%[1]s/%[2]s/${JOB_NAME}%[3]s"`, dir, database, BackupFileSuffix)},
+			Env: backup.generateBackupDatabaseEnv(databases[database]),
+			VolumeMounts: []corev1.VolumeMount{
+				{Name: BackupVolumeName, MountPath: BackupMountPath},
+				{Name: DatabaseSSLVolumeName, MountPath: DatabaseSSLMountPath, ReadOnly: true},
+			},
+		})
+	}
+
+	if target.Kind == BackupTargetPVC {
+		script := []string{"set -e"}
+		for _, database := range names {
+			script = append(script, fmt.Sprintf(`ls -1 %s/%s/*%s | %s | xargs -r rm -f`, dir, database, BackupFileSuffix, prune))
+		}
+
+		pod.Containers = []corev1.Container{{
+			Name:    "prune",
+			Image:   backup.getBackupImage(),
+			Command: []string{"/bin/sh", "-c"},
+			Args:    []string{strings.Join(script, "\n")},
+			VolumeMounts: []corev1.VolumeMount{
+				{Name: BackupVolumeName, MountPath: BackupMountPath},
+			},
+		}}
+		pod.Volumes = []corev1.Volume{{
+			Name: BackupVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: target.ClaimName},
+			},
+		}, backup.generateDatabaseSSLVolume()}
+		return pod
+	}
+
+	script := []string{`set -e
+opts=""
+if [ "${MC_INSECURE}" = "true" ]; then opts="--insecure"; fi`}
+	for _, database := range names {
+		dest := fmt.Sprintf("%s/${BUCKET}/%s/%s", BackupStorageAlias, backup.getBackupPrefix(), database)
+		script = append(script, fmt.Sprintf(`mc ${opts} cp "%[1]s/%[2]s/${JOB_NAME}%[3]s" "%[4]s/"
+mc ${opts} ls "%[4]s/" | awk '{print $NF}' | grep '%[3]s$' | %[5]s | while read f; do mc ${opts} rm "%[4]s/${f}"; done`,
+			dir, database, BackupFileSuffix, dest, prune))
+	}
+
+	pod.Containers = []corev1.Container{{
+		Name:    "upload",
+		Image:   DefaultBackupUploaderImage,
+		Command: []string{"/bin/sh", "-c"},
+		Args:    []string{strings.Join(script, "\n")},
+		Env:     []corev1.EnvVar{generateJobNameEnv()},
+		EnvFrom: []corev1.EnvFromSource{{
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: storageSecret},
+			},
+		}},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: BackupVolumeName, MountPath: BackupMountPath},
+		},
+	}}
+	pod.Volumes = []corev1.Volume{{
+		Name: BackupVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	}, backup.generateDatabaseSSLVolume()}
+	return pod
+}
+
+// generateBackupDatabaseEnv returns the libpq environments from the component database secret.
+// The primary service is connected instead of the connection pooler, since pg_dump and pg_restore
+// rely on the session state. The ssl mode is taken from spec, and the certificates are read from
+// the ssl config mounted by generateDatabaseSSLVolume, libpq ignores the missing files if the ssl mode does not verify the server.
+func (postgres *PostgreSQLReconciler) generateBackupDatabaseEnv(secretName string) []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		generateJobNameEnv(),
+		{Name: "PGSSLMODE", Value: postgres.GetSSLMode()},
+		{Name: "PGSSLROOTCERT", Value: path.Join(DatabaseSSLMountPath, SSLCAKey)},
+		{Name: "PGSSLCERT", Value: path.Join(DatabaseSSLMountPath, SSLCertKey)},
+		{Name: "PGSSLKEY", Value: path.Join(DatabaseSSLMountPath, SSLKeyKey)},
+	}
+
+	keys := map[string]string{
+		"PGHOST":     "host",
+		"PGPORT":     "port",
+		"PGDATABASE": "database",
+		"PGUSER":     "username",
+		"PGPASSWORD": "password",
+	}
+	if postgres.IsConnectionPoolerEnabled() {
+		delete(keys, "PGHOST")
+		env = append(env, corev1.EnvVar{Name: "PGHOST", Value: postgres.GetPrimaryHost()})
+	}
+
+	for name, key := range keys {
+		env = append(env, corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  key,
+				},
+			},
+		})
+	}
+
+	sort.Slice(env, func(i, j int) bool {
+		return env[i].Name < env[j].Name
+	})
+	return env
+}
+
+// generateDatabaseSSLVolume returns the volume of the certificates in the ssl config of spec,
+// the keys are optional since the client certificate is not always required. The volume is empty without ssl config.
+func (postgres *PostgreSQLReconciler) generateDatabaseSSLVolume() corev1.Volume {
+	sslConfig := postgres.HarborCluster.Spec.Database.Spec.SslConfig
+	if sslConfig == "" {
+		return corev1.Volume{
+			Name: DatabaseSSLVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		}
+	}
+
+	optional := true
+	mode := DatabaseSSLFileMode
+	return corev1.Volume{
+		Name: DatabaseSSLVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: sslConfig,
+				Items: []corev1.KeyToPath{
+					{Key: SSLCAKey, Path: SSLCAKey},
+					{Key: SSLCertKey, Path: SSLCertKey},
+					{Key: SSLKeyKey, Path: SSLKeyKey},
+				},
+				DefaultMode: &mode,
+				Optional:    &optional,
+			},
+		},
+	}
+}
+
+func generateJobNameEnv() corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: "JOB_NAME",
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{
+				FieldPath: "metadata.labels['job-name']",
+			},
+		},
+	}
+}
+
+func (backup *BackupReconciler) getBackupLabels(database string) map[string]string {
+	labels := map[string]string{
+		k8s.HarborClusterNameLabel: backup.HarborCluster.Name,
+	}
+	if database != "" {
+		labels[BackupDatabaseLabel] = database
+	}
+	return labels
+}
+
+func (backup *BackupReconciler) getBackupListOptions() *client.ListOptions {
+	selector := labels1.SelectorFromSet(backup.getBackupLabels(""))
+	requirement, _ := labels1.NewRequirement(BackupDatabaseLabel, selection.Exists, nil)
+
+	return &client.ListOptions{
+		Namespace:     backup.HarborCluster.Namespace,
+		LabelSelector: selector.Add(*requirement),
+	}
+}
+
+func (backup *BackupReconciler) getBackupPrefix() string {
+	prefix := strings.Trim(backup.HarborCluster.Spec.Database.Spec.Backup.Target.Prefix, "/")
+	if prefix == "" {
+		return DefaultBackupPrefix
+	}
+	return prefix
+}
+
+func (backup *BackupReconciler) getBackupRetention() int {
+	if backup.HarborCluster.Spec.Database.Spec.Backup.Retention <= 0 {
+		return DefaultBackupRetention
+	}
+	return backup.HarborCluster.Spec.Database.Spec.Backup.Retention
+}
+
+func (backup *BackupReconciler) getBackupImage() string {
+	if backup.HarborCluster.Spec.Database.Spec.Backup.Image != "" {
+		return backup.HarborCluster.Spec.Database.Spec.Backup.Image
+	}
+	return fmt.Sprintf("postgres:%s-alpine", backup.GetPostgreVersion())
+}
+
+func databaseBackupNotReadyStatus(reason, message string) *lcm.CRStatus {
+	return lcm.New(goharborv1.DatabaseBackupReady).
+		WithStatus(corev1.ConditionFalse).
+		WithReason(reason).
+		WithMessage(message)
+}
@@ -0,0 +1,211 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// InsufficientPrivilegeCode is the postgres error code of insufficient_privilege
+	InsufficientPrivilegeCode = "42501"
+
+	// LegacyDatabaseOwner owned the inCluster databases before each component has its own user
+	LegacyDatabaseOwner = "zalando"
+)
+
+// ownershipQueries return the statements transferring the database and the objects of harbor in the public schema
+// from the legacy owners ($2) to the database owner ($1). The sequences are queried after the tables,
+// since the sequences of serial columns are transferred with their tables.
+var ownershipQueries = []string{
+	`SELECT format('ALTER DATABASE %I OWNER TO %I', d.datname, $1::text)
+	FROM pg_database d
+	WHERE d.datname = current_database() AND pg_get_userbyid(d.datdba) = ANY($2)`,
+	`SELECT format('ALTER SCHEMA %I OWNER TO %I', n.nspname, $1::text)
+	FROM pg_namespace n
+	WHERE n.nspname = 'public' AND pg_get_userbyid(n.nspowner) = ANY($2)`,
+	`SELECT format('ALTER %s %I.%I OWNER TO %I',
+		CASE c.relkind WHEN 'v' THEN 'VIEW' WHEN 'm' THEN 'MATERIALIZED VIEW' ELSE 'TABLE' END, n.nspname, c.relname, $1::text)
+	FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE n.nspname = 'public' AND c.relkind IN ('r', 'p', 'v', 'm') AND pg_get_userbyid(c.relowner) = ANY($2)
+		AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e')`,
+	`SELECT format('ALTER SEQUENCE %I.%I OWNER TO %I', n.nspname, c.relname, $1::text)
+	FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE n.nspname = 'public' AND c.relkind = 'S' AND pg_get_userbyid(c.relowner) = ANY($2)
+		AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e')`,
+	`SELECT format('ALTER FUNCTION %I.%I(%s) OWNER TO %I', n.nspname, p.proname, pg_get_function_identity_arguments(p.oid), $1::text)
+	FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+	WHERE n.nspname = 'public' AND pg_get_userbyid(p.proowner) = ANY($2)
+		AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND d.deptype = 'e')`,
+}
+
+// EnsureDatabases creates the missing databases of harbor components and their owners,
+// and transfers the existing databases to their owners. It is idempotent.
+func (postgres *PostgreSQLReconciler) EnsureDatabases(client *pgx.Conn, databases map[string]string) error {
+	names := make([]string, 0, len(databases))
+	for name := range databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		owner := databases[name]
+		db := pgx.Identifier{name}.Sanitize()
+		role := pgx.Identifier{owner}.Sanitize()
+
+		if err := postgres.ensureRole(client, owner); err != nil {
+			return err
+		}
+
+		var current string
+		err := client.QueryRow(postgres.Ctx,
+			"SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = $1", name).Scan(&current)
+		if errors.Is(err, pgx.ErrNoRows) {
+			postgres.Log.Info("Creating database.",
+				"namespace", postgres.HarborCluster.Namespace, "database", name, "owner", owner)
+			if _, err := client.Exec(postgres.Ctx, fmt.Sprintf("CREATE DATABASE %s OWNER %s", db, role)); err != nil {
+				return err
+			}
+			if _, err := client.Exec(postgres.Ctx, fmt.Sprintf("REVOKE ALL ON DATABASE %s FROM PUBLIC", db)); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if current == owner {
+			continue
+		}
+
+		postgres.Log.Info("Transferring database owner.",
+			"namespace", postgres.HarborCluster.Namespace, "database", name, "from", current, "to", owner)
+		if _, err := client.Exec(postgres.Ctx, fmt.Sprintf("ALTER DATABASE %s OWNER TO %s", db, role)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureRole creates the role if it does not exist, and grants it to the admin,
+// so that the admin is able to create and transfer the objects owned by it.
+func (postgres *PostgreSQLReconciler) ensureRole(client *pgx.Conn, role string) error {
+	exists, err := postgres.exists(client, "SELECT 1 FROM pg_roles WHERE rolname = $1", role)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		postgres.Log.Info("Creating database role.",
+			"namespace", postgres.HarborCluster.Namespace, "role", role)
+		if _, err := client.Exec(postgres.Ctx, fmt.Sprintf("CREATE ROLE %s", pgx.Identifier{role}.Sanitize())); err != nil {
+			return err
+		}
+	}
+
+	member, err := postgres.exists(client, "SELECT 1 WHERE pg_has_role(current_user, $1, 'MEMBER')", role)
+	if err != nil || member {
+		return err
+	}
+	_, err = client.Exec(postgres.Ctx, fmt.Sprintf("GRANT %s TO CURRENT_USER", pgx.Identifier{role}.Sanitize()))
+	return err
+}
+
+// EnsureLogin sets the password of the component role only if the component can not connect with it,
+// e.g. the role is created without login, or the password in the component secret is regenerated.
+func (postgres *PostgreSQLReconciler) EnsureLogin(client *pgx.Conn, conn *Connect) error {
+	if err := postgres.verifyConn(conn); err == nil {
+		return nil
+	}
+
+	postgres.Log.Info("Setting password of database user.",
+		"namespace", postgres.HarborCluster.Namespace, "user", conn.Username)
+	_, err := client.Exec(postgres.Ctx, fmt.Sprintf("ALTER ROLE %s WITH LOGIN PASSWORD %s",
+		pgx.Identifier{conn.Username}.Sanitize(), quoteLiteral(conn.Password)))
+	return err
+}
+
+// MigrateOwnership transfers the objects of harbor in the database from the legacy owners to the database owner.
+// The objects were created by the shared user before each component has its own user. REASSIGN OWNED is not used,
+// since it transfers the shared objects of the legacy owners as well, e.g. the databases of the other components.
+func (postgres *PostgreSQLReconciler) MigrateOwnership(admin *Connect, database, owner string, legacyOwners []string) error {
+	conn := *admin
+	conn.Database = database
+	client, err := conn.NewClient(postgres.Ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close(postgres.Ctx)
+
+	for _, query := range ownershipQueries {
+		statements, err := postgres.queryStatements(client, query, owner, legacyOwners)
+		if err != nil {
+			return err
+		}
+
+		for _, statement := range statements {
+			postgres.Log.Info("Transferring database object owner.",
+				"namespace", postgres.HarborCluster.Namespace, "database", database, "statement", statement)
+			if _, err := client.Exec(postgres.Ctx, statement); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (postgres *PostgreSQLReconciler) queryStatements(client *pgx.Conn, sql string, args ...interface{}) ([]string, error) {
+	rows, err := client.Query(postgres.Ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statements []string
+	for rows.Next() {
+		var statement string
+		if err := rows.Scan(&statement); err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+	return statements, rows.Err()
+}
+
+func (postgres *PostgreSQLReconciler) verifyConn(conn *Connect) error {
+	client, err := conn.NewClient(postgres.Ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close(postgres.Ctx)
+
+	return client.Ping(postgres.Ctx)
+}
+
+func (postgres *PostgreSQLReconciler) exists(client *pgx.Conn, sql string, args ...interface{}) (bool, error) {
+	var one int
+	err := client.QueryRow(postgres.Ctx, sql, args...).Scan(&one)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// quoteLiteral quotes the string as a sql literal, the statements like ALTER ROLE do not accept parameters.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// IsInsufficientPrivilege returns whether the error is caused by missing privileges
+func IsInsufficientPrivilege(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == InsufficientPrivilegeCode
+}
@@ -1,6 +1,26 @@
 package database
 
-import "fmt"
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	SSLModeDisable    = "disable"
+	SSLModeRequire    = "require"
+	SSLModeVerifyCA   = "verify-ca"
+	SSLModeVerifyFull = "verify-full"
+
+	SSLCAKey   = "ca.crt"
+	SSLCertKey = "tls.crt"
+	SSLKeyKey  = "tls.key"
+)
 
 type Connect struct {
 	Host     string
@@ -8,10 +28,115 @@ type Connect struct {
 	Password string
 	Username string
 	Database string
+
+	SSLMode        string
+	ConnectTimeout int
+	CACert         []byte
+	ClientCert     []byte
+	ClientKey      []byte
 }
 
 // GenDatabaseUrl returns database connection url
 func (c *Connect) GenDatabaseUrl() string {
 	databaseURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", c.Username, c.Password, c.Host, c.Port, c.Database)
+
+	params := url.Values{}
+	if c.SSLMode != "" {
+		params.Set("sslmode", c.SSLMode)
+	}
+	if c.ConnectTimeout > 0 {
+		params.Set("connect_timeout", fmt.Sprintf("%d", c.ConnectTimeout))
+	}
+	if len(params) > 0 {
+		databaseURL = fmt.Sprintf("%s?%s", databaseURL, params.Encode())
+	}
+
 	return databaseURL
 }
+
+// GetSSLMode returns the ssl mode of the connection, default is disable
+func (c *Connect) GetSSLMode() string {
+	if c.SSLMode == "" {
+		return SSLModeDisable
+	}
+	return c.SSLMode
+}
+
+// NewClient returns the database connection,
+// the certificates are loaded from memory instead of the sslrootcert and sslcert files.
+func (c *Connect) NewClient(ctx context.Context) (*pgx.Conn, error) {
+	config, err := pgx.ParseConfig(c.GenDatabaseUrl())
+	if err != nil {
+		return nil, err
+	}
+
+	if config.TLSConfig != nil {
+		tlsConfig, err := c.GenTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		config.TLSConfig = tlsConfig
+	}
+
+	return pgx.ConnectConfig(ctx, config)
+}
+
+// GenTLSConfig returns the tls config of the ssl mode
+func (c *Connect) GenTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if len(c.ClientCert) > 0 || len(c.ClientKey) > 0 {
+		cert, err := tls.X509KeyPair(c.ClientCert, c.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	switch c.SSLMode {
+	case SSLModeVerifyCA, SSLModeVerifyFull:
+		if len(c.CACert) == 0 {
+			return nil, fmt.Errorf("%s is required for sslmode %s", SSLCAKey, c.SSLMode)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(c.CACert) {
+			return nil, errors.New("unable to parse the database CA certificate")
+		}
+		tlsConfig.RootCAs = pool
+	default:
+		tlsConfig.InsecureSkipVerify = true
+		return tlsConfig, nil
+	}
+
+	if c.SSLMode == SSLModeVerifyFull {
+		tlsConfig.ServerName = c.Host
+		return tlsConfig, nil
+	}
+
+	// verify-ca checks the certificate chain only, the host name is not verified.
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("database server presents no certificate")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[i] = cert
+		}
+		opts := x509.VerifyOptions{
+			Roots:         tlsConfig.RootCAs,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(opts)
+		return err
+	}
+
+	return tlsConfig, nil
+}
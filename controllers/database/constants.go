@@ -8,12 +8,71 @@ const (
 	GetDatabaseCrError                = "Get database CR error"
 	SetOwnerReferenceError            = "Set owner reference error"
 	DefaultUnstructuredConverterError = "Default unstructured converter error"
+	EnsureDatabaseError               = "Ensure database error"
+	DatabasePrivilegeError            = "Insufficient database privilege"
 )
 
 const (
-	DownScalingDatabase     = "DatabaseDownScaling"
-	UpScalingDatabase       = "DatabaseUpScaling"
-	RollingUpgradesDatabase = "DatabaseRollingUpgrades"
+	GetBackupStorageError          = "Get backup storage error"
+	CreateBackupStorageSecretError = "Create backup storage secret error"
+	CreateBackupCronJobError       = "Create backup CronJob error"
+	GetBackupJobError              = "Get backup Job error"
+	DatabaseBackupFailedError      = "Database backup failed"
+)
+
+const (
+	WaitStorageError           = "Wait for storage"
+	DeployWalSecretError       = "Deploy WAL secret error"
+	CheckConnectionPoolerError = "Check connection pooler error"
+)
+
+// DatabaseDegradedReason is the reason of ready condition when the database is serving
+// but the cluster is not fully healthy.
+const DatabaseDegradedReason = "Database degraded"
+
+const (
+	GetHarborCRError           = "Get harbor CR error"
+	StopHarborError            = "Stop harbor error"
+	SuspendBackupError         = "Suspend backup error"
+	CreateRestoreJobError      = "Create restore Job error"
+	DatabaseRestoreFailedError = "Database restore failed"
+
+	RestoringDatabase = "DatabaseRestoring"
+	RestoredDatabase  = "DatabaseRestored"
+
+	MessageDatabaseRestoring = "Database restore %s started."
+	MessageDatabaseRestored  = "Database restore %s completed."
+)
+
+const (
+	RotateDatabaseCredentialError         = "Rotate database credential error"
+	RollComponentError                    = "Roll component error"
+	DatabaseCredentialRotationFailedError = "Database credential rotation failed"
+
+	RotatingDatabaseCredential = "DatabaseCredentialRotating"
+	RotatedDatabaseCredential  = "DatabaseCredentialRotated"
+
+	MessageDatabaseCredentialRotating = "Database credential rotation of %s started."
+	MessageDatabaseCredentialRotated  = "Database credential rotation of %s completed."
+)
+
+const (
+	DatabaseReplicasBelowMinimumError = "Database replicas below minimum"
+	GetDatabasePodError               = "Get database pod error"
+	SwitchoverDatabaseError           = "Switchover database error"
+	DatabaseVolumeSizeError           = "Database volume size error"
+	DatabaseVolumeShrinkError         = "Database volume shrink rejected"
+	GetDatabaseVolumeError            = "Get database volume error"
+	DatabaseVolumeExpansionError      = "Database volume expansion not allowed"
+	ExpandDatabaseVolumeError         = "Expand database volume error"
+)
+
+const (
+	DownScalingDatabase        = "DatabaseDownScaling"
+	UpScalingDatabase          = "DatabaseUpScaling"
+	RollingUpgradesDatabase    = "DatabaseRollingUpgrades"
+	ExpandingDatabaseVolume    = "DatabaseVolumeExpanding"
+	DatabaseNotHighlyAvailable = "DatabaseNotHighlyAvailable"
 
 	MessageDatabaseCreate = "Database  %s already created."
 
@@ -22,6 +81,9 @@ const (
 	MessageDatabaseDownScaling     = "Database downscale from %d to %d"
 	MessageDatabaseUpScaling       = "Database upscale from %d to %d"
 	MessageDatabaseRollingUpgrades = "Database resource from %s to %s"
+	MessageDatabaseVolumeExpanding = "Database volume expand from %s to %s"
+
+	MessageDatabaseNotHighlyAvailable = "Database is scaled to %d instance, it can not fail over."
 )
 
 const (
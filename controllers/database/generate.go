@@ -40,32 +40,32 @@ func (postgres *PostgreSQLReconciler) generatePostgresCR() (*unstructured.Unstru
 			},
 			TeamID:            postgres.HarborCluster.Namespace,
 			NumberOfInstances: replica,
-			Users: map[string]api.UserFlags{
-				"zalando": {
-					"superuser",
-					"createdb",
-				},
-				"foo_user": {},
-			},
-			Patroni: api.Patroni{
-				InitDB: map[string]string{
-					"encoding":       "UTF8",
-					"locale":         "en_US.UTF-8",
-					"data-checksums": "true",
-				},
-				PgHba: []string{
-					"hostssl all all 0.0.0.0/0 md5",
-					"host    all all 0.0.0.0/0 md5",
-				},
-			},
-			Databases: databases,
+			Users:             postgres.GetUsers(),
+			Patroni:           postgres.GetPostgrePatroni(),
+			Databases:         databases,
 			PostgresqlParam: api.PostgresqlParam{
-				PgVersion: version,
+				PgVersion:  version,
+				Parameters: postgres.GetPostgreParameters(),
 			},
 			Resources: resource,
 		},
 	}
 
+	if postgres.IsConnectionPoolerEnabled() {
+		enabled := true
+		conf.Spec.EnableConnectionPooler = &enabled
+		conf.Spec.ConnectionPooler = postgres.GetConnectionPooler()
+	}
+
+	if postgres.IsWalStorageRequired() {
+		config, err := postgres.getWalStorageConfig()
+		if err != nil {
+			return nil, err
+		}
+		conf.Spec.Env = postgres.GenWalEnv(config)
+		conf.Spec.Clone = postgres.GenCloneDescription(config)
+	}
+
 	mapResult, err := runtime.DefaultUnstructuredConverter.ToUnstructured(conf)
 	if err != nil {
 		return nil, err
@@ -76,7 +76,16 @@ func (postgres *PostgreSQLReconciler) generatePostgresCR() (*unstructured.Unstru
 	return &data, nil
 }
 
-//generateHarborDatabaseSecret returns database connection secret
+// sslModeComponents are the harbor components whose ssl mode is rendered by harbor operator,
+// core does not read the ssl mode from its database secret.
+var sslModeComponents = map[string]bool{
+	HarborClair:        true,
+	HarborNotaryServer: true,
+	HarborNotarySigner: true,
+}
+
+//generateHarborDatabaseSecret returns database connection secret.
+// The certificates are not kept in it, since harbor operator does not mount them into the harbor components.
 func (postgres *PostgreSQLReconciler) generateHarborDatabaseSecret(conn *Connect, secretName, propertyName string) *corev1.Secret {
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -93,18 +102,8 @@ func (postgres *PostgreSQLReconciler) generateHarborDatabaseSecret(conn *Connect
 		},
 	}
 
-	if propertyName == HarborClair {
-		secret.StringData["database"] = ClairDatabase
-		secret.StringData["ssl"] = "disable"
-	}
-
-	if propertyName == HarborNotaryServer {
-		secret.StringData["database"] = NotaryServerDatabase
-		secret.StringData["ssl"] = "disable"
-	}
-	if propertyName == HarborNotarySigner {
-		secret.StringData["database"] = NotarySignerDatabase
-		secret.StringData["ssl"] = "disable"
+	if sslModeComponents[propertyName] {
+		secret.StringData["ssl"] = conn.GetSSLMode()
 	}
 
 	return secret
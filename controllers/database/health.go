@@ -0,0 +1,178 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/goharbor/harbor-cluster-operator/controllers/database/api"
+	"github.com/jackc/pgx/v4"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels1 "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	PostgresClusterStatusRunning = "Running"
+
+	SpiloRoleMaster  = "master"
+	SpiloRoleReplica = "replica"
+
+	ReplicationStateStreaming = "streaming"
+
+	// DefaultMaximumReplicationLag is the default maximum_lag_on_failover of Patroni in bytes
+	DefaultMaximumReplicationLag = 1048576
+)
+
+// ReplicationStat is a row of pg_stat_replication
+type ReplicationStat struct {
+	ApplicationName string
+	State           string
+	Lag             int64
+}
+
+// CheckInClusterHealth checks the inCluster database beyond the connection.
+// It does:
+// - read the status of postgresql CR
+// - check there is exactly one Patroni leader
+// - count the running instances against NumberOfInstances
+// - check the replicas are streaming and their lag is under maximum_lag_on_failover
+// It returns an error if the database is not serving, otherwise the reasons why the database is degraded.
+func (postgres *PostgreSQLReconciler) CheckInClusterHealth(conn *pgx.Conn) ([]string, error) {
+	var degraded []string
+
+	clusterStatus, err := postgres.GetPostgresClusterStatus()
+	if err != nil {
+		return nil, err
+	}
+	if clusterStatus != PostgresClusterStatusRunning {
+		degraded = append(degraded, fmt.Sprintf("postgresql status is %q", clusterStatus))
+	}
+
+	masters, replicas, err := postgres.GetSpiloPods()
+	if err != nil {
+		return nil, err
+	}
+	if len(masters) > 1 {
+		return nil, fmt.Errorf("found %d Patroni leaders", len(masters))
+	}
+	if len(masters) == 0 {
+		degraded = append(degraded, "no Patroni leader")
+	}
+
+	running := len(masters) + len(replicas)
+	if expected := int(postgres.GetPostgreReplica()); running < expected {
+		degraded = append(degraded, fmt.Sprintf("%d of %d instances are running", running, expected))
+	}
+
+	stats, err := postgres.GetReplicationStats(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	maxLag := postgres.getMaximumReplicationLag()
+	for _, stat := range stats {
+		if stat.State != ReplicationStateStreaming {
+			degraded = append(degraded, fmt.Sprintf("replica %s is %s", stat.ApplicationName, stat.State))
+			continue
+		}
+		if stat.Lag > maxLag {
+			degraded = append(degraded, fmt.Sprintf("replica %s lags %d bytes", stat.ApplicationName, stat.Lag))
+		}
+	}
+	if len(stats) < len(replicas) {
+		degraded = append(degraded, fmt.Sprintf("%d of %d replicas are replicating", len(stats), len(replicas)))
+	}
+
+	return degraded, nil
+}
+
+// GetPostgresClusterStatus returns the status of postgresql CR reported by postgres operator
+func (postgres *PostgreSQLReconciler) GetPostgresClusterStatus() (string, error) {
+	actualCR := postgres.ActualCR
+	if actualCR == nil {
+		crdClient := postgres.DClient.WithResource(databaseFailoversGVR).WithNamespace(postgres.HarborCluster.Namespace)
+		cr, err := crdClient.Get(postgres.GetDatabaseName(), metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		actualCR = cr
+	}
+
+	var pg api.Postgresql
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(actualCR.UnstructuredContent(), &pg); err != nil {
+		return "", err
+	}
+	return pg.Status.PostgresClusterStatus, nil
+}
+
+// GetSpiloPods returns the running and ready leader and replica pods of inCluster database
+func (postgres *PostgreSQLReconciler) GetSpiloPods() ([]corev1.Pod, []corev1.Pod, error) {
+	opts := &client.ListOptions{
+		Namespace: postgres.HarborCluster.Namespace,
+		LabelSelector: labels1.SelectorFromSet(map[string]string{
+			"application":  "spilo",
+			"cluster-name": postgres.GetDatabaseName(),
+		}),
+	}
+	pods := &corev1.PodList{}
+	if err := postgres.Client.List(opts, pods); err != nil {
+		return nil, nil, err
+	}
+
+	var masters, replicas []corev1.Pod
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || !isPodReady(&pod) {
+			continue
+		}
+		switch pod.Labels["spilo-role"] {
+		case SpiloRoleMaster:
+			masters = append(masters, pod)
+		case SpiloRoleReplica:
+			replicas = append(replicas, pod)
+		}
+	}
+	return masters, replicas, nil
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// GetReplicationStats returns the replicas connected to the leader and their replay lag in bytes
+func (postgres *PostgreSQLReconciler) GetReplicationStats(conn *pgx.Conn) ([]ReplicationStat, error) {
+	rows, err := conn.Query(postgres.Ctx,
+		`SELECT application_name, state,
+			COALESCE(pg_wal_lsn_diff(pg_current_wal_lsn(), replay_lsn), 0)::bigint
+		FROM pg_stat_replication`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []ReplicationStat
+	for rows.Next() {
+		var stat ReplicationStat
+		if err := rows.Scan(&stat.ApplicationName, &stat.State, &stat.Lag); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+func (postgres *PostgreSQLReconciler) getMaximumReplicationLag() int64 {
+	spec := postgres.HarborCluster.Spec.Database.Spec
+	if spec != nil && spec.Patroni != nil && spec.Patroni.MaximumLagOnFailover != nil {
+		return *spec.Patroni.MaximumLagOnFailover
+	}
+	return DefaultMaximumReplicationLag
+}
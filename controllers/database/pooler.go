@@ -0,0 +1,77 @@
+package database
+
+import (
+	"fmt"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/controllers/database/api"
+)
+
+const (
+	DefaultConnectionPoolerReplicas int32 = 2
+	// DefaultConnectionPoolerMode is session, harbor components rely on the session state in transaction mode,
+	// e.g. the prepared statements of core and the advisory locks of the schema migrations.
+	DefaultConnectionPoolerMode = "session"
+)
+
+// IsConnectionPoolerEnabled returns whether harbor components connect to inCluster database through the pooler
+func (postgres *PostgreSQLReconciler) IsConnectionPoolerEnabled() bool {
+	if postgres.HarborCluster.Spec.Database.Kind != goharborv1.InClusterComponent {
+		return false
+	}
+	spec := postgres.HarborCluster.Spec.Database.Spec
+	return spec != nil && spec.ConnectionPooler != nil && spec.ConnectionPooler.Enabled
+}
+
+// GetConnectionPooler returns the connection pooler of postgresql CR
+func (postgres *PostgreSQLReconciler) GetConnectionPooler() *api.ConnectionPooler {
+	spec := postgres.HarborCluster.Spec.Database.Spec.ConnectionPooler
+
+	replicas := DefaultConnectionPoolerReplicas
+	if spec.Replicas != nil {
+		replicas = *spec.Replicas
+	}
+
+	mode := spec.Mode
+	if mode == "" {
+		mode = DefaultConnectionPoolerMode
+	}
+
+	pooler := &api.ConnectionPooler{
+		NumberOfInstances: &replicas,
+		Mode:              mode,
+		DockerImage:       spec.Image,
+		Resources: api.Resources{
+			ResourceRequests: getResourceDescription(spec.Resources.Requests),
+			ResourceLimits:   getResourceDescription(spec.Resources.Limits),
+		},
+	}
+
+	if spec.MaxDBConnections != nil {
+		maxDBConnections := *spec.MaxDBConnections
+		pooler.MaxDBConnections = &maxDBConnections
+	}
+
+	return pooler
+}
+
+// GetConnectionPoolerHost returns the service of connection pooler created by postgres operator
+func (postgres *PostgreSQLReconciler) GetConnectionPoolerHost() string {
+	return fmt.Sprintf("%s-pooler.%s.svc", postgres.GetDatabaseName(), postgres.HarborCluster.Namespace)
+}
+
+// GetPrimaryHost returns the service of the primary instance created by postgres operator
+func (postgres *PostgreSQLReconciler) GetPrimaryHost() string {
+	return fmt.Sprintf("%s.%s.svc", postgres.GetDatabaseName(), postgres.HarborCluster.Namespace)
+}
+
+// CheckConnectionPooler pings the database through the connection pooler with the component user
+func (postgres *PostgreSQLReconciler) CheckConnectionPooler(conn *Connect) error {
+	client, err := conn.NewClient(postgres.Ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close(postgres.Ctx)
+
+	return client.Ping(postgres.Ctx)
+}
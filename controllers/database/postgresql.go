@@ -7,6 +7,7 @@ import (
 	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
 	"github.com/goharbor/harbor-cluster-operator/controllers/k8s"
 	"github.com/goharbor/harbor-cluster-operator/lcm"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -26,6 +27,8 @@ type PostgreSQLReconciler struct {
 	ExpectCR      *unstructured.Unstructured
 	ActualCR      *unstructured.Unstructured
 	Labels        map[string]string
+
+	ComponentToCRStatus map[goharborv1.Component]*lcm.CRStatus
 }
 
 // Reconciler implements the reconcile logic of postgreSQL service
@@ -36,6 +39,14 @@ func (postgres *PostgreSQLReconciler) Reconcile() (*lcm.CRStatus, error) {
 
 	crdClient := postgres.DClient.WithResource(databaseFailoversGVR).WithNamespace(postgres.HarborCluster.Namespace)
 	if postgres.HarborCluster.Spec.Database.Kind == goharborv1.InClusterComponent {
+		if postgres.IsWalStorageRequired() {
+			if _, err := postgres.getStorageSecretName(); err != nil {
+				return databaseNotReadyStatus(WaitStorageError, err.Error()), nil
+			}
+			if err := postgres.DeployWalSecret(); err != nil {
+				return databaseNotReadyStatus(DeployWalSecretError, err.Error()), err
+			}
+		}
 
 		name := fmt.Sprintf("%s-%s", postgres.HarborCluster.Namespace, postgres.HarborCluster.Name)
 		actualCR, err := crdClient.Get(name, metav1.GetOptions{})
@@ -56,14 +67,30 @@ func (postgres *PostgreSQLReconciler) Reconcile() (*lcm.CRStatus, error) {
 		postgres.ActualCR = actualCR
 		postgres.ExpectCR = expectCR
 
-		crStatus, err := postgres.Update()
+		isScale, err := postgres.IsScalingEvent()
 		if err != nil {
-			return crStatus, err
+			return databaseNotReadyStatus(DefaultUnstructuredConverterError, err.Error()), err
+		}
+
+		// the other changes are left to the next reconcile after scaling.
+		if isScale {
+			crStatus, err := postgres.Scale()
+			if err != nil || crStatus.Condition.Status == corev1.ConditionFalse {
+				return crStatus, err
+			}
+		} else {
+			crStatus, err := postgres.Update()
+			if err != nil {
+				return crStatus, err
+			}
 		}
 	}
 
 	crStatus, err := postgres.Readiness()
 	if err != nil {
+		if crStatus != nil {
+			return crStatus, err
+		}
 		return databaseNotReadyStatus(CheckDatabaseHealthError, err.Error()), err
 	}
 
@@ -77,15 +104,3 @@ func (postgres *PostgreSQLReconciler) Provision() (*lcm.CRStatus, error) {
 func (postgres *PostgreSQLReconciler) Delete() (*lcm.CRStatus, error) {
 	panic("implement me")
 }
-
-func (postgres *PostgreSQLReconciler) Scale() (*lcm.CRStatus, error) {
-	panic("implement me")
-}
-
-func (postgres *PostgreSQLReconciler) ScaleUp(newReplicas uint64) (*lcm.CRStatus, error) {
-	panic("implement me")
-}
-
-func (postgres *PostgreSQLReconciler) ScaleDown(newReplicas uint64) (*lcm.CRStatus, error) {
-	panic("implement me")
-}
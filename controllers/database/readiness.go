@@ -3,6 +3,7 @@ package database
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
 	"github.com/goharbor/harbor-cluster-operator/controllers/k8s"
@@ -65,6 +66,26 @@ func (postgres *PostgreSQLReconciler) Readiness() (*lcm.CRStatus, error) {
 		postgres.Log.Error(err, "Fail to check Database.", "namespace", postgres.HarborCluster.Namespace, "name", postgres.HarborCluster.Name)
 		return nil, err
 	}
+
+	var degraded []string
+	if postgres.HarborCluster.Spec.Database.Kind == goharborv1.InClusterComponent {
+		degraded, err = postgres.CheckInClusterHealth(client)
+		if err != nil {
+			postgres.Log.Error(err, "Fail to check Database cluster.", "namespace", postgres.HarborCluster.Namespace, "name", postgres.HarborCluster.Name)
+			return databaseNotReadyStatus(CheckDatabaseHealthError, err.Error()), err
+		}
+	}
+
+	if postgres.HarborCluster.Spec.Database.Kind == goharborv1.ExternalComponent {
+		if err := postgres.EnsureDatabases(client, postgres.GetDatabases()); err != nil {
+			postgres.Log.Error(err, "Fail to ensure databases.", "namespace", postgres.HarborCluster.Namespace, "name", postgres.HarborCluster.Name)
+			if IsInsufficientPrivilege(err) {
+				return databaseNotReadyStatus(DatabasePrivilegeError, err.Error()), err
+			}
+			return databaseNotReadyStatus(EnsureDatabaseError, err.Error()), err
+		}
+	}
+
 	postgres.Log.Info("Database already ready.", "namespace", postgres.HarborCluster.Namespace, "name", postgres.HarborCluster.Name)
 
 	properties := &lcm.Properties{}
@@ -80,14 +101,49 @@ func (postgres *PostgreSQLReconciler) Readiness() (*lcm.CRStatus, error) {
 	}
 
 	for key, component := range components {
-		secretName := getComponentSecretName(component)
 		propertyName := getPropertyName(key)
-		if err := postgres.DeployComponentSecret(conn, component, secretName, key); err != nil {
+		if user := postgres.getActiveUser(key); user != componentDatabases[key] {
+			// the alternate role and its secret are maintained by credential rotation
+			properties.Add(propertyName, getUserSecretName(key, user))
+			continue
+		}
+
+		secretName := getComponentSecretName(component)
+
+		componentConn, err := postgres.GetComponentConn(client, conn, key, secretName)
+		if err != nil {
+			postgres.Log.Error(err, "Fail to ensure database user.",
+				"namespace", postgres.HarborCluster.Namespace, "name", postgres.HarborCluster.Name, "component", key)
+			if IsInsufficientPrivilege(err) {
+				return databaseNotReadyStatus(DatabasePrivilegeError, err.Error()), err
+			}
+			return databaseNotReadyStatus(EnsureDatabaseError, err.Error()), err
+		}
+
+		if postgres.IsConnectionPoolerEnabled() {
+			if err := postgres.CheckConnectionPooler(componentConn); err != nil {
+				postgres.Log.Error(err, "Fail to check connection pooler.",
+					"namespace", postgres.HarborCluster.Namespace, "name", postgres.HarborCluster.Name, "component", key)
+				return databaseNotReadyStatus(CheckConnectionPoolerError, err.Error()), err
+			}
+		}
+
+		if err := postgres.DeployComponentSecret(componentConn, component, secretName, key); err != nil {
 			return nil, err
 		}
 		properties.Add(propertyName, secretName)
 	}
 
+	if len(degraded) > 0 {
+		postgres.Log.Info("Database is degraded.", "namespace", postgres.HarborCluster.Namespace, "name", postgres.HarborCluster.Name,
+			"reasons", degraded)
+		return lcm.New(goharborv1.DatabaseReady).
+			WithStatus(corev1.ConditionTrue).
+			WithReason(DatabaseDegradedReason).
+			WithMessage(strings.Join(degraded, "; ")).
+			WithProperties(*properties), nil
+	}
+
 	crStatus := lcm.New(goharborv1.DatabaseReady).
 		WithStatus(corev1.ConditionTrue).
 		WithReason("database already ready").
@@ -128,7 +184,31 @@ func (postgres *PostgreSQLReconciler) DeployComponentSecret(conn *Connect, compo
 		}
 		return err
 	}
-	return nil
+
+	if isSecretDataEqual(secret.Data, sc.StringData) {
+		return nil
+	}
+
+	postgres.Log.Info("Updating Harbor Component Secret",
+		"namespace", postgres.HarborCluster.Namespace,
+		"name", secretName,
+		"component", component)
+	secret.Data = nil
+	secret.StringData = sc.StringData
+	return postgres.Client.Update(secret)
+}
+
+// isSecretDataEqual returns whether the data of secret is the same as the expected string data
+func isSecretDataEqual(data map[string][]byte, stringData map[string]string) bool {
+	if len(data) != len(stringData) {
+		return false
+	}
+	for k, v := range stringData {
+		if actual, ok := data[k]; !ok || string(actual) != v {
+			return false
+		}
+	}
+	return true
 }
 
 // GetExternalDatabaseInfo returns external database connection client
@@ -147,9 +227,7 @@ func (postgres *PostgreSQLReconciler) GetExternalDatabaseInfo() (*Connect, *pgx.
 		return connect, client, err
 	}
 
-	url := connect.GenDatabaseUrl()
-
-	client, err = pgx.Connect(postgres.Ctx, url)
+	client, err = connect.NewClient(postgres.Ctx)
 	if err != nil {
 		postgres.Log.Error(err, "Unable to connect to database")
 		return connect, client, err
@@ -186,9 +264,7 @@ func (postgres *PostgreSQLReconciler) GetInClusterDatabaseInfo() (*Connect, *pgx
 		return connect, client, err
 	}
 
-	url := connect.GenDatabaseUrl()
-
-	client, err = pgx.Connect(postgres.Ctx, url)
+	client, err = connect.NewClient(postgres.Ctx)
 	if err != nil {
 		postgres.Log.Error(err, "Unable to connect to database")
 		return connect, client, err
@@ -210,11 +286,20 @@ func (postgres *PostgreSQLReconciler) GetInClusterDatabaseConn(name, pw string)
 		Username: InClusterDatabaseUserName,
 		Database: InClusterDatabaseName,
 	}
+
+	if err := postgres.SetConnectSSL(conn); err != nil {
+		return nil, err
+	}
 	return conn, nil
 }
 
 func GenInClusterPasswordSecretName(teamID, name string) string {
-	return fmt.Sprintf("postgres.%s-%s.credentials", teamID, name)
+	return GenInClusterUserSecretName(InClusterDatabaseUserName, teamID, name)
+}
+
+// GenInClusterUserSecretName returns the name of secret which postgres operator stores the password of user in
+func GenInClusterUserSecretName(username, teamID, name string) string {
+	return fmt.Sprintf("%s.%s-%s.credentials", username, teamID, name)
 }
 
 // GetInClusterHost returns the Database master pod ip or service name
@@ -242,9 +327,14 @@ func (postgres *PostgreSQLReconciler) GetDatabaseName() string {
 
 // GetInClusterDatabasePassword is get inCluster postgresql password
 func (postgres *PostgreSQLReconciler) GetInClusterDatabasePassword() (string, error) {
+	return postgres.GetInClusterUserPassword(InClusterDatabaseUserName)
+}
+
+// GetInClusterUserPassword is get the password of inCluster postgresql user
+func (postgres *PostgreSQLReconciler) GetInClusterUserPassword(username string) (string, error) {
 	var pw string
 
-	secretName := GenInClusterPasswordSecretName(postgres.HarborCluster.Namespace, postgres.HarborCluster.Name)
+	secretName := GenInClusterUserSecretName(username, postgres.HarborCluster.Namespace, postgres.HarborCluster.Name)
 	secret, err := postgres.GetSecret(secretName)
 	if err != nil {
 		return pw, err
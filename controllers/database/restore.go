@@ -0,0 +1,533 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/lcm"
+	harborv1 "github.com/goharbor/harbor-operator/api/v1alpha1"
+	"github.com/jackc/pgx/v4"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	RestorePhaseStopping  = "Stopping"
+	RestorePhaseRestoring = "Restoring"
+	RestorePhaseVerifying = "Verifying"
+	RestorePhaseCompleted = "Completed"
+	RestorePhaseFailed    = "Failed"
+
+	// InitialRestoreID is the id of restore on the creation of HarborCluster
+	InitialRestoreID = "initial"
+
+	RestoreDatabaseLabel = "goharbor.io/database-restore"
+	RestoreIDAnnotation  = "goharbor.io/database-restore-id"
+	RestoreFileName      = "restore.dump"
+
+	BackupLocationS3  = "s3"
+	BackupLocationPVC = "pvc"
+)
+
+// RestoreReconciler restores harbor databases from the backups.
+type RestoreReconciler struct {
+	BackupReconciler
+}
+
+// Reconcile reconcile will restore harbor databases from backups before harbor components start.
+// It does:
+// - suspend the backups and wait for the running backup to finish
+// - stop harbor by deleting the Harbor CR
+// - run a restore Job against each harbor database
+// - verify the restored databases by the tables and the schema version
+// - return ready status so that harbor is reconciled again
+// It returns nil status if no restore is requested.
+func (restore *RestoreReconciler) Reconcile() (*lcm.CRStatus, error) {
+	restore.Client.WithContext(restore.Ctx)
+
+	id, err := restore.getRestoreID()
+	if err != nil {
+		return databaseRestoreNotReadyStatus(GetHarborCRError, err.Error()), err
+	}
+	if id == "" {
+		return nil, nil
+	}
+
+	status := restore.HarborCluster.Status.DatabaseRestore
+	if status == nil || status.ID != id {
+		now := metav1.Now()
+		status = &goharborv1.DatabaseRestoreStatus{
+			ID:        id,
+			Phase:     RestorePhaseStopping,
+			Message:   "stopping harbor",
+			StartTime: &now,
+		}
+		restore.HarborCluster.Status.DatabaseRestore = status
+		restore.Recorder.Event(restore.HarborCluster, corev1.EventTypeNormal, RestoringDatabase,
+			fmt.Sprintf(MessageDatabaseRestoring, id))
+	}
+
+	switch status.Phase {
+	case RestorePhaseStopping:
+		return restore.StopHarbor()
+	case RestorePhaseRestoring:
+		return restore.RestoreDatabases()
+	case RestorePhaseVerifying:
+		return restore.VerifyDatabases()
+	case RestorePhaseFailed:
+		return databaseRestoreNotReadyStatus(DatabaseRestoreFailedError, status.Message), nil
+	}
+
+	return lcm.New(goharborv1.DatabaseRestored).
+		WithStatus(corev1.ConditionTrue).
+		WithReason("database restored").
+		WithMessage(status.Message), nil
+}
+
+// getRestoreID returns the id of requested restore.
+// The restore in spec only applies to a new HarborCluster whose harbor has never been provisioned,
+// the existing HarborCluster is restored by the annotation.
+func (restore *RestoreReconciler) getRestoreID() (string, error) {
+	if id := restore.HarborCluster.Annotations[goharborv1.DatabaseRestoreAnnotation]; id != "" {
+		return id, nil
+	}
+
+	if restore.HarborCluster.Spec.Database.Spec == nil || restore.HarborCluster.Spec.Database.Spec.Restore == nil {
+		return "", nil
+	}
+
+	if status := restore.HarborCluster.Status.DatabaseRestore; status != nil {
+		return status.ID, nil
+	}
+
+	exists, err := restore.isHarborCRExists()
+	if err != nil || exists {
+		return "", err
+	}
+	return InitialRestoreID, nil
+}
+
+// StopHarbor deletes the Harbor CR, so that harbor components do not write to the databases during restore.
+// The Harbor CR is provisioned again once the restore is completed.
+func (restore *RestoreReconciler) StopHarbor() (*lcm.CRStatus, error) {
+	running, err := restore.SuspendBackups()
+	if err != nil {
+		return databaseRestoreNotReadyStatus(SuspendBackupError, err.Error()), err
+	}
+	if running {
+		return databaseRestoringStatus("waiting for the running backup to finish"), nil
+	}
+
+	harborCR := &harborv1.Harbor{}
+	err = restore.Client.Get(restore.getHarborCRNamespacedName(), harborCR)
+	if kerr.IsNotFound(err) {
+		restore.setRestorePhase(RestorePhaseRestoring, "restoring databases")
+		return restore.RestoreDatabases()
+	} else if err != nil {
+		return databaseRestoreNotReadyStatus(GetHarborCRError, err.Error()), err
+	}
+
+	if harborCR.DeletionTimestamp == nil {
+		restore.Log.Info("Stopping harbor to restore databases.",
+			"namespace", harborCR.Namespace, "name", harborCR.Name)
+		if err := restore.Client.Delete(harborCR, client.PropagationPolicy(metav1.DeletePropagationForeground)); err != nil && !kerr.IsNotFound(err) {
+			return databaseRestoreNotReadyStatus(StopHarborError, err.Error()), err
+		}
+	}
+
+	return databaseRestoringStatus("waiting for harbor to stop"), nil
+}
+
+// SuspendBackups suspends the backup CronJobs until the restore is completed or failed,
+// they are resumed by the backup reconciler. It returns whether any backup Job is still running.
+func (restore *RestoreReconciler) SuspendBackups() (bool, error) {
+	cronJobs := &batchv1beta1.CronJobList{}
+	if err := restore.Client.List(restore.getBackupListOptions(), cronJobs); err != nil {
+		return false, err
+	}
+
+	for i := range cronJobs.Items {
+		cronJob := &cronJobs.Items[i]
+		if isSuspended(cronJob) {
+			continue
+		}
+		restore.Log.Info("Suspending database backup CronJob.",
+			"namespace", cronJob.Namespace, "name", cronJob.Name)
+		suspend := true
+		cronJob.Spec.Suspend = &suspend
+		if err := restore.Client.Update(cronJob); err != nil {
+			return false, err
+		}
+	}
+
+	jobs := &batchv1.JobList{}
+	if err := restore.Client.List(restore.getBackupListOptions(), jobs); err != nil {
+		return false, err
+	}
+	for _, job := range jobs.Items {
+		if job.Status.Active > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RestoreDatabases runs a restore Job against each harbor database in turn and waits for them to finish
+func (restore *RestoreReconciler) RestoreDatabases() (*lcm.CRStatus, error) {
+	locations, err := restore.getRestoreLocations()
+	if err != nil {
+		restore.setRestorePhase(RestorePhaseFailed, err.Error())
+		return databaseRestoreNotReadyStatus(DatabaseRestoreFailedError, err.Error()), nil
+	}
+
+	var storageSecret string
+	for _, location := range locations {
+		if !strings.HasPrefix(location, BackupLocationS3+"://") {
+			continue
+		}
+		secretName, err := restore.getStorageSecretName()
+		if err != nil {
+			return databaseRestoreNotReadyStatus(GetBackupStorageError, err.Error()), nil
+		}
+		if storageSecret, err = restore.DeployBackupStorageSecret(secretName); err != nil {
+			return databaseRestoreNotReadyStatus(CreateBackupStorageSecretError, err.Error()), err
+		}
+		break
+	}
+
+	databases := restore.GetBackupDatabases()
+	names := make([]string, 0, len(databases))
+	for database := range databases {
+		names = append(names, database)
+	}
+	sort.Strings(names)
+
+	// the databases are restored one by one, so that the restore Jobs do not mount the backup claim concurrently.
+	for _, database := range names {
+		job, err := restore.generateRestoreJob(database, databases[database], storageSecret, locations[database])
+		if err != nil {
+			restore.setRestorePhase(RestorePhaseFailed, err.Error())
+			return databaseRestoreNotReadyStatus(DatabaseRestoreFailedError, err.Error()), nil
+		}
+
+		done, err := restore.DeployRestoreJob(job)
+		if err != nil {
+			return databaseRestoreNotReadyStatus(CreateRestoreJobError, err.Error()), err
+		}
+		if restore.HarborCluster.Status.DatabaseRestore.Phase == RestorePhaseFailed {
+			return databaseRestoreNotReadyStatus(DatabaseRestoreFailedError,
+				restore.HarborCluster.Status.DatabaseRestore.Message), nil
+		}
+		if !done {
+			return databaseRestoringStatus(fmt.Sprintf("restoring database %s", database)), nil
+		}
+	}
+
+	restore.setRestorePhase(RestorePhaseVerifying, "verifying databases")
+	return restore.VerifyDatabases()
+}
+
+// DeployRestoreJob creates the restore Job of current restore, the Job of previous restore is deleted first.
+// It returns whether the Job is finished.
+func (restore *RestoreReconciler) DeployRestoreJob(job *batchv1.Job) (bool, error) {
+	if err := controllerutil.SetControllerReference(restore.HarborCluster, job, restore.Scheme); err != nil {
+		return false, err
+	}
+
+	actual := &batchv1.Job{}
+	err := restore.Client.Get(types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, actual)
+	if kerr.IsNotFound(err) {
+		restore.Log.Info("Creating database restore Job.",
+			"namespace", job.Namespace, "name", job.Name)
+		return false, restore.Client.Create(job)
+	} else if err != nil {
+		return false, err
+	}
+
+	if actual.Annotations[RestoreIDAnnotation] != job.Annotations[RestoreIDAnnotation] {
+		if actual.DeletionTimestamp == nil {
+			restore.Log.Info("Deleting database restore Job of previous restore.",
+				"namespace", actual.Namespace, "name", actual.Name)
+			err := restore.Client.Delete(actual, client.PropagationPolicy(metav1.DeletePropagationForeground))
+			if err != nil && !kerr.IsNotFound(err) {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	if getJobFinishedTime(actual, batchv1.JobFailed) != nil {
+		restore.setRestorePhase(RestorePhaseFailed,
+			fmt.Sprintf("restore Job %s failed, annotate %s with a new value to retry", actual.Name, goharborv1.DatabaseRestoreAnnotation))
+		return true, nil
+	}
+
+	return getJobFinishedTime(actual, batchv1.JobComplete) != nil, nil
+}
+
+// VerifyDatabases checks the restored databases have tables and their schema migrations are not dirty
+func (restore *RestoreReconciler) VerifyDatabases() (*lcm.CRStatus, error) {
+	databases := restore.GetBackupDatabases()
+	names := make([]string, 0, len(databases))
+	for database := range databases {
+		names = append(names, database)
+	}
+	sort.Strings(names)
+
+	var versions []string
+	for _, database := range names {
+		conn, err := restore.GetDatabaseConn(databases[database])
+		if err != nil {
+			return databaseRestoreNotReadyStatus(CheckDatabaseHealthError, err.Error()), err
+		}
+
+		c, err := conn.NewClient(restore.Ctx)
+		if err != nil {
+			return databaseRestoreNotReadyStatus(CheckDatabaseHealthError, err.Error()), err
+		}
+
+		version, err := restore.verifyDatabase(c)
+		c.Close(restore.Ctx)
+		if err != nil {
+			msg := fmt.Sprintf("database %s is invalid after restore: %s", database, err.Error())
+			restore.setRestorePhase(RestorePhaseFailed, msg)
+			return databaseRestoreNotReadyStatus(DatabaseRestoreFailedError, msg), nil
+		}
+		if version != "" {
+			versions = append(versions, fmt.Sprintf("%s schema version %s", database, version))
+		}
+	}
+
+	msg := "harbor databases are restored"
+	if len(versions) > 0 {
+		msg = fmt.Sprintf("%s, %s", msg, strings.Join(versions, ", "))
+	}
+
+	now := metav1.Now()
+	restore.HarborCluster.Status.DatabaseRestore.CompletionTime = &now
+	restore.setRestorePhase(RestorePhaseCompleted, msg)
+	restore.Recorder.Event(restore.HarborCluster, corev1.EventTypeNormal, RestoredDatabase,
+		fmt.Sprintf(MessageDatabaseRestored, restore.HarborCluster.Status.DatabaseRestore.ID))
+
+	return lcm.New(goharborv1.DatabaseRestored).
+		WithStatus(corev1.ConditionTrue).
+		WithReason("database restored").
+		WithMessage(msg), nil
+}
+
+// verifyDatabase returns the schema version of database if it is managed by migrations
+func (restore *RestoreReconciler) verifyDatabase(c *pgx.Conn) (string, error) {
+	var tables int
+	err := c.QueryRow(restore.Ctx,
+		"SELECT count(*) FROM information_schema.tables WHERE table_schema = 'public'").Scan(&tables)
+	if err != nil {
+		return "", err
+	}
+	if tables == 0 {
+		return "", errors.New("no table is restored")
+	}
+
+	migrations, err := restore.exists(c,
+		"SELECT 1 FROM information_schema.tables WHERE table_schema = 'public' AND table_name = 'schema_migrations'")
+	if err != nil || !migrations {
+		return "", err
+	}
+
+	var (
+		version int64
+		dirty   bool
+	)
+	err = c.QueryRow(restore.Ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", errors.New("schema version is missing")
+	} else if err != nil {
+		return "", err
+	}
+	if dirty {
+		return "", fmt.Errorf("schema version %d is dirty", version)
+	}
+
+	return fmt.Sprintf("%d", version), nil
+}
+
+// getRestoreLocations returns the backup location of each harbor database,
+// the database not listed in spec is restored from its last successful backup.
+func (restore *RestoreReconciler) getRestoreLocations() (map[string]string, error) {
+	var backups map[string]string
+	if spec := restore.HarborCluster.Spec.Database.Spec; spec != nil && spec.Restore != nil {
+		backups = spec.Restore.Backups
+	}
+
+	locations := map[string]string{}
+	var missing []string
+	for database := range restore.GetBackupDatabases() {
+		location := backups[database]
+		if location == "" {
+			for _, backup := range restore.HarborCluster.Status.DatabaseBackups {
+				if backup.Database == database {
+					location = backup.Location
+				}
+			}
+		}
+		if location == "" {
+			missing = append(missing, database)
+			continue
+		}
+		locations[database] = location
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("no backup to restore databases %s", strings.Join(missing, ", "))
+	}
+	return locations, nil
+}
+
+func (restore *RestoreReconciler) generateRestoreJob(database, databaseSecret, storageSecret, location string) (*batchv1.Job, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || path == "" {
+		return nil, fmt.Errorf("invalid backup location %s", location)
+	}
+
+	restoreContainer := corev1.Container{
+		Name:    "pg-restore",
+		Image:   restore.getRestoreImage(),
+		Command: []string{"/bin/sh", "-c"},
+		Env:     restore.generateBackupDatabaseEnv(databaseSecret),
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: BackupVolumeName, MountPath: BackupMountPath, ReadOnly: u.Scheme == BackupLocationPVC},
+			{Name: DatabaseSSLVolumeName, MountPath: DatabaseSSLMountPath, ReadOnly: true},
+		},
+	}
+
+	pod := corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+	}
+
+	switch u.Scheme {
+	case BackupLocationPVC:
+		restoreContainer.Args = []string{generateRestoreScript(fmt.Sprintf("%s/%s", BackupMountPath, path))}
+		pod.Volumes = []corev1.Volume{{
+			Name: BackupVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: u.Host, ReadOnly: true},
+			},
+		}}
+	case BackupLocationS3:
+		restoreContainer.Args = []string{generateRestoreScript(fmt.Sprintf("%s/%s", BackupMountPath, RestoreFileName))}
+		pod.InitContainers = []corev1.Container{{
+			Name:    "download",
+			Image:   DefaultBackupUploaderImage,
+			Command: []string{"/bin/sh", "-c"},
+			Args: []string{fmt.Sprintf(`set -e
+opts=""
+if [ "${MC_INSECURE}" = "true" ]; then opts="--insecure"; fi
+mc ${opts} cp "%s/%s/%s" "%s/%s"`, BackupStorageAlias, u.Host, path, BackupMountPath, RestoreFileName)},
+			EnvFrom: []corev1.EnvFromSource{{
+				SecretRef: &corev1.SecretEnvSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: storageSecret},
+				},
+			}},
+			VolumeMounts: []corev1.VolumeMount{
+				{Name: BackupVolumeName, MountPath: BackupMountPath},
+			},
+		}}
+		pod.Volumes = []corev1.Volume{{
+			Name: BackupVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		}}
+	default:
+		return nil, fmt.Errorf("unsupported backup location %s", location)
+	}
+	pod.Containers = []corev1.Container{restoreContainer}
+	pod.Volumes = append(pod.Volumes, restore.generateDatabaseSSLVolume())
+
+	labels := restore.getBackupLabels("")
+	labels[RestoreDatabaseLabel] = database
+	backoffLimit := backupJobBackoffLimit
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-restore-%s", restore.HarborCluster.Name, database),
+			Namespace: restore.HarborCluster.Namespace,
+			Labels:    labels,
+			Annotations: map[string]string{
+				RestoreIDAnnotation: restore.HarborCluster.Status.DatabaseRestore.ID,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: pod,
+			},
+		},
+	}, nil
+}
+
+// generateRestoreScript returns the script restoring the dump file,
+// the objects are owned by the component user instead of the user in dump.
+func generateRestoreScript(file string) string {
+	return fmt.Sprintf(`set -e
+pg_restore --clean --if-exists --no-owner --no-privileges -d "${PGDATABASE}" "%s"`, file)
+}
+
+func (restore *RestoreReconciler) getRestoreImage() string {
+	if spec := restore.HarborCluster.Spec.Database.Spec; spec != nil && spec.Restore != nil && spec.Restore.Image != "" {
+		return spec.Restore.Image
+	}
+	return fmt.Sprintf("postgres:%s-alpine", restore.GetPostgreVersion())
+}
+
+func (restore *RestoreReconciler) setRestorePhase(phase, message string) {
+	restore.HarborCluster.Status.DatabaseRestore.Phase = phase
+	restore.HarborCluster.Status.DatabaseRestore.Message = message
+}
+
+func (restore *RestoreReconciler) isHarborCRExists() (bool, error) {
+	err := restore.Client.Get(restore.getHarborCRNamespacedName(), &harborv1.Harbor{})
+	if kerr.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// getHarborCRNamespacedName returns the name of Harbor CR provisioned by the harbor reconciler
+func (restore *RestoreReconciler) getHarborCRNamespacedName() types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: restore.HarborCluster.Namespace,
+		Name:      fmt.Sprintf("%s-harbor", restore.HarborCluster.Name),
+	}
+}
+
+func databaseRestoringStatus(message string) *lcm.CRStatus {
+	return lcm.New(goharborv1.DatabaseRestored).
+		WithStatus(corev1.ConditionFalse).
+		WithReason("database restoring").
+		WithMessage(message)
+}
+
+func databaseRestoreNotReadyStatus(reason, message string) *lcm.CRStatus {
+	return lcm.New(goharborv1.DatabaseRestored).
+		WithStatus(corev1.ConditionFalse).
+		WithReason(reason).
+		WithMessage(message)
+}
@@ -0,0 +1,412 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/controllers/common"
+	"github.com/goharbor/harbor-cluster-operator/controllers/k8s"
+	"github.com/goharbor/harbor-cluster-operator/lcm"
+	"github.com/jackc/pgx/v4"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	RotationPhaseRolling   = "Rolling"
+	RotationPhaseCompleted = "Completed"
+	RotationPhaseFailed    = "Failed"
+
+	// AlternateUserSuffix is the suffix of the login role alternated with the database owner,
+	// the role is a member of the owner and acts as the owner by default.
+	AlternateUserSuffix = "_alt"
+	// AlternateSecretSuffix is the suffix of the component secret of the alternate role
+	AlternateSecretSuffix = "alt"
+)
+
+var (
+	// componentDeployments maps harbor components to the suffix of deployments created by harbor operator
+	componentDeployments = map[string][]string{
+		HarborCore:         {"core"},
+		HarborClair:        {"clair"},
+		HarborNotaryServer: {"notary-server"},
+		HarborNotarySigner: {"notary-signer"},
+	}
+)
+
+// CredentialRotationReconciler rotates the database passwords of harbor components.
+type CredentialRotationReconciler struct {
+	PostgreSQLReconciler
+
+	client *pgx.Conn
+}
+
+// Reconcile reconcile will rotate the database passwords requested by annotation or schedule.
+// Each component alternates between two login roles, so that the running pods keep working during the rotation.
+// It does:
+// - set a new password of the standby role, and verify it by a new connection
+// - switch the component secret read by harbor to the standby role, which rolls the component through the Harbor CR
+// - disable the previous role once the deployments of the component are rolled out with the new secret
+// - record the progress of each component in status
+// It returns nil status if rotation is never requested.
+func (rotation *CredentialRotationReconciler) Reconcile() (*lcm.CRStatus, error) {
+	rotation.Client.WithContext(rotation.Ctx)
+
+	spec := rotation.HarborCluster.Spec.Database.Spec
+	if rotation.HarborCluster.Annotations[goharborv1.DatabaseCredentialRotationAnnotation] == "" &&
+		(spec == nil || spec.CredentialRotation == nil) &&
+		len(rotation.HarborCluster.Status.DatabaseCredentials) == 0 {
+		return nil, nil
+	}
+
+	defer func() {
+		if rotation.client != nil {
+			rotation.client.Close(rotation.Ctx)
+		}
+	}()
+
+	var statuses []goharborv1.DatabaseCredentialStatus
+	for _, component := range rotation.getComponents() {
+		status := goharborv1.DatabaseCredentialStatus{Component: component}
+		for _, s := range rotation.HarborCluster.Status.DatabaseCredentials {
+			if s.Component == component {
+				status = s
+			}
+		}
+
+		id, requested := rotation.getRequestedRotationID(component, &status)
+		if requested || rotation.isRotationDue(&status) {
+			if requested {
+				status.RotationID = id
+			}
+			if err := rotation.Rotate(component, &status); err != nil {
+				rotation.HarborCluster.Status.DatabaseCredentials = rotation.mergeStatuses(append(statuses, status))
+				return databaseCredentialNotReadyStatus(RotateDatabaseCredentialError, err.Error()), err
+			}
+		} else if status.Phase == RotationPhaseRolling {
+			if err := rotation.CheckRollout(component, &status); err != nil {
+				rotation.HarborCluster.Status.DatabaseCredentials = rotation.mergeStatuses(append(statuses, status))
+				return databaseCredentialNotReadyStatus(RollComponentError, err.Error()), err
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	rotation.HarborCluster.Status.DatabaseCredentials = statuses
+
+	var failed, rolling []string
+	for _, status := range statuses {
+		switch status.Phase {
+		case RotationPhaseFailed:
+			failed = append(failed, fmt.Sprintf("%s: %s", status.Component, status.Message))
+		case RotationPhaseRolling:
+			rolling = append(rolling, status.Component)
+		}
+	}
+
+	if len(failed) > 0 {
+		return databaseCredentialNotReadyStatus(DatabaseCredentialRotationFailedError, strings.Join(failed, "; ")), nil
+	}
+	if len(rolling) > 0 {
+		return lcm.New(goharborv1.DatabaseCredentialRotated).
+			WithStatus(corev1.ConditionUnknown).
+			WithReason("rolling components").
+			WithMessage(fmt.Sprintf("rolling components %s with the new database credentials", strings.Join(rolling, ", "))), nil
+	}
+	return lcm.New(goharborv1.DatabaseCredentialRotated).
+		WithStatus(corev1.ConditionTrue).
+		WithReason("database credential rotated").
+		WithMessage("the last database credential rotations are completed."), nil
+}
+
+// Rotate sets a new database password of the standby role and switches the component to it.
+// The failure of the new password is recorded in status instead of returned.
+func (rotation *CredentialRotationReconciler) Rotate(component string, status *goharborv1.DatabaseCredentialStatus) error {
+	now := metav1.Now()
+	status.StartTime = &now
+	rotation.Recorder.Event(rotation.HarborCluster, corev1.EventTypeNormal, RotatingDatabaseCredential,
+		fmt.Sprintf(MessageDatabaseCredentialRotating, component))
+
+	if err := rotation.connectAdmin(); err != nil {
+		return err
+	}
+
+	standby, err := rotation.RotatePassword(component)
+	if err != nil {
+		rotation.Log.Error(err, "Fail to rotate database credential.",
+			"namespace", rotation.HarborCluster.Namespace, "name", rotation.HarborCluster.Name, "component", component)
+		status.Phase = RotationPhaseFailed
+		status.Message = err.Error()
+		return nil
+	}
+
+	status.User = standby
+	status.Phase = RotationPhaseRolling
+	status.Message = fmt.Sprintf("rolling the component with the new password of user %s", standby)
+	return nil
+}
+
+// RotatePassword sets a new password of the standby role of the component, and writes it to the component secret
+// of the standby role. The running pods keep using the current role, which is untouched.
+// The new password is verified by a new connection before the secret is written, the standby role is
+// disabled again if it fails. It returns the standby role.
+func (rotation *CredentialRotationReconciler) RotatePassword(component string) (string, error) {
+	current := rotation.getActiveUser(component)
+	standby := getStandbyUser(component, current)
+	secretName := getUserSecretName(component, current)
+
+	conn, err := rotation.GetDatabaseConn(secretName)
+	if err != nil {
+		return "", err
+	}
+	if conn.Username != current {
+		return "", fmt.Errorf("secret %s does not belong to database user %s", secretName, current)
+	}
+
+	conn.Username = standby
+	conn.Password = common.RandomString(DatabasePasswordLength, common.LowerStringRandomType)
+
+	if err := rotation.ensureLoginRole(component, standby, conn.Password); err != nil {
+		if IsInsufficientPrivilege(err) {
+			return "", fmt.Errorf("%s: %s", DatabasePrivilegeError, err.Error())
+		}
+		return "", err
+	}
+
+	if err := rotation.verifyConn(conn); err != nil {
+		if derr := rotation.disableRole(standby); derr != nil {
+			return "", fmt.Errorf("new password can not connect: %v, and fail to disable user %s: %v", err, standby, derr)
+		}
+		return "", fmt.Errorf("new password can not connect: %v", err)
+	}
+
+	// postgres operator only manages the database owner
+	if rotation.HarborCluster.Spec.Database.Kind == goharborv1.InClusterComponent && standby == componentDatabases[component] {
+		userSecret := GenInClusterUserSecretName(standby, rotation.HarborCluster.Namespace, rotation.HarborCluster.Name)
+		if err := rotation.updateSecretPassword(userSecret, InClusterDatabasePasswordKey, conn.Password); err != nil {
+			return "", err
+		}
+	}
+
+	if err := rotation.DeployComponentSecret(conn, componentSecretNames[component], getUserSecretName(component, standby), component); err != nil {
+		return "", err
+	}
+	return standby, nil
+}
+
+// CheckRollout completes the rotation once the deployments of the component are rolled out with the secret
+// of the new role, and the previous role is disabled. The deployments are rolled by harbor operator,
+// since the database secret in Harbor CR is switched by the database readiness.
+func (rotation *CredentialRotationReconciler) CheckRollout(component string, status *goharborv1.DatabaseCredentialStatus) error {
+	secretName := getUserSecretName(component, status.User)
+	for _, deployment := range rotation.getComponentDeployments(component) {
+		actual := &appsv1.Deployment{}
+		err := rotation.Client.Get(deployment, actual)
+		if kerr.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if !isDeploymentUsingSecret(actual, secretName) || !k8s.IsDeploymentRolledOut(actual) {
+			return nil
+		}
+	}
+
+	if err := rotation.connectAdmin(); err != nil {
+		return err
+	}
+	previous := getStandbyUser(component, status.User)
+	if err := rotation.disableRole(previous); err != nil {
+		return err
+	}
+
+	now := metav1.Now()
+	status.Phase = RotationPhaseCompleted
+	status.Message = fmt.Sprintf("the component is rolled with the new password of user %s", status.User)
+	status.LastRotationTime = &now
+	rotation.Recorder.Event(rotation.HarborCluster, corev1.EventTypeNormal, RotatedDatabaseCredential,
+		fmt.Sprintf(MessageDatabaseCredentialRotated, component))
+	return nil
+}
+
+// isDeploymentUsingSecret returns whether the pod template of deployment reads the secret
+func isDeploymentUsingSecret(deployment *appsv1.Deployment, secretName string) bool {
+	for _, volume := range deployment.Spec.Template.Spec.Volumes {
+		if volume.Secret != nil && volume.Secret.SecretName == secretName {
+			return true
+		}
+	}
+	for _, container := range deployment.Spec.Template.Spec.Containers {
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == secretName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getRequestedRotationID returns the rotation requested by annotation which has not been handled
+func (rotation *CredentialRotationReconciler) getRequestedRotationID(component string, status *goharborv1.DatabaseCredentialStatus) (string, bool) {
+	id := rotation.HarborCluster.Annotations[goharborv1.DatabaseCredentialRotationAnnotation]
+	if id == "" || id == status.RotationID {
+		return "", false
+	}
+
+	selected := rotation.HarborCluster.Annotations[goharborv1.DatabaseCredentialRotationComponentsAnnotation]
+	if selected == "" {
+		return id, true
+	}
+	for _, c := range strings.Split(selected, ",") {
+		if strings.TrimSpace(c) == component {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// isRotationDue returns whether the interval has elapsed since the last rotation,
+// a failed rotation is retried after the interval as well.
+func (rotation *CredentialRotationReconciler) isRotationDue(status *goharborv1.DatabaseCredentialStatus) bool {
+	spec := rotation.HarborCluster.Spec.Database.Spec
+	if spec == nil || spec.CredentialRotation == nil || spec.CredentialRotation.Interval.Duration <= 0 {
+		return false
+	}
+	if status.Phase == RotationPhaseRolling {
+		return false
+	}
+
+	last := rotation.HarborCluster.CreationTimestamp.Time
+	if status.LastRotationTime != nil {
+		last = status.LastRotationTime.Time
+	}
+	if status.Phase == RotationPhaseFailed && status.StartTime != nil && status.StartTime.After(last) {
+		last = status.StartTime.Time
+	}
+
+	return time.Since(last) >= spec.CredentialRotation.Interval.Duration
+}
+
+// getComponents returns the harbor components which own databases
+func (rotation *CredentialRotationReconciler) getComponents() []string {
+	databases := rotation.GetDatabases()
+
+	var components []string
+	for component, database := range componentDatabases {
+		if _, ok := databases[database]; ok {
+			components = append(components, component)
+		}
+	}
+	sort.Strings(components)
+	return components
+}
+
+func (rotation *CredentialRotationReconciler) getComponentDeployments(component string) []types.NamespacedName {
+	var deployments []types.NamespacedName
+	for _, suffix := range componentDeployments[component] {
+		deployments = append(deployments, types.NamespacedName{
+			Namespace: rotation.HarborCluster.Namespace,
+			Name:      fmt.Sprintf("%s-harbor-%s", rotation.HarborCluster.Name, suffix),
+		})
+	}
+	return deployments
+}
+
+// mergeStatuses keeps the statuses of the components which are not handled yet
+func (rotation *CredentialRotationReconciler) mergeStatuses(statuses []goharborv1.DatabaseCredentialStatus) []goharborv1.DatabaseCredentialStatus {
+	handled := map[string]bool{}
+	for _, status := range statuses {
+		handled[status.Component] = true
+	}
+	for _, status := range rotation.HarborCluster.Status.DatabaseCredentials {
+		if !handled[status.Component] {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// connectAdmin opens the admin connection once per reconcile
+func (rotation *CredentialRotationReconciler) connectAdmin() error {
+	if rotation.client != nil {
+		return nil
+	}
+
+	var err error
+	switch rotation.HarborCluster.Spec.Database.Kind {
+	case goharborv1.ExternalComponent:
+		_, rotation.client, err = rotation.GetExternalDatabaseInfo()
+	case goharborv1.InClusterComponent:
+		_, rotation.client, err = rotation.GetInClusterDatabaseInfo()
+	default:
+		err = fmt.Errorf("unsupported database kind %s", rotation.HarborCluster.Spec.Database.Kind)
+	}
+	return err
+}
+
+// ensureLoginRole sets the password of the login role of component. The alternate role is created as a member
+// of the database owner, and it acts as the owner by default, so that the objects created by it are owned by the owner.
+func (rotation *CredentialRotationReconciler) ensureLoginRole(component, username, password string) error {
+	owner := componentDatabases[component]
+	role := pgx.Identifier{username}.Sanitize()
+
+	exists, err := rotation.exists(rotation.client, "SELECT 1 FROM pg_roles WHERE rolname = $1", username)
+	if err != nil {
+		return err
+	}
+	if exists {
+		_, err = rotation.client.Exec(rotation.Ctx, fmt.Sprintf("ALTER ROLE %s WITH LOGIN PASSWORD %s", role, quoteLiteral(password)))
+		return err
+	}
+
+	rotation.Log.Info("Creating alternate database user.",
+		"namespace", rotation.HarborCluster.Namespace, "user", username, "owner", owner)
+	ownerRole := pgx.Identifier{owner}.Sanitize()
+	if _, err := rotation.client.Exec(rotation.Ctx, fmt.Sprintf("CREATE ROLE %s WITH LOGIN PASSWORD %s IN ROLE %s",
+		role, quoteLiteral(password), ownerRole)); err != nil {
+		return err
+	}
+	_, err = rotation.client.Exec(rotation.Ctx, fmt.Sprintf("ALTER ROLE %s SET role TO %s", role, quoteLiteral(owner)))
+	return err
+}
+
+// disableRole disables the login of the role which is no longer used by the component
+func (rotation *CredentialRotationReconciler) disableRole(username string) error {
+	exists, err := rotation.exists(rotation.client, "SELECT 1 FROM pg_roles WHERE rolname = $1", username)
+	if err != nil || !exists {
+		return err
+	}
+
+	rotation.Log.Info("Disabling database user.",
+		"namespace", rotation.HarborCluster.Namespace, "user", username)
+	_, err = rotation.client.Exec(rotation.Ctx, fmt.Sprintf("ALTER ROLE %s WITH NOLOGIN", pgx.Identifier{username}.Sanitize()))
+	return err
+}
+
+func (rotation *CredentialRotationReconciler) updateSecretPassword(secretName, key, password string) error {
+	secret := &corev1.Secret{}
+	err := rotation.Client.Get(types.NamespacedName{Name: secretName, Namespace: rotation.HarborCluster.Namespace}, secret)
+	if err != nil {
+		return err
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[key] = []byte(password)
+	return rotation.Client.Update(secret)
+}
+
+func databaseCredentialNotReadyStatus(reason, message string) *lcm.CRStatus {
+	return lcm.New(goharborv1.DatabaseCredentialRotated).
+		WithStatus(corev1.ConditionFalse).
+		WithReason(reason).
+		WithMessage(message)
+}
@@ -0,0 +1,284 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newRotationReconciler(annotations map[string]string, spec *goharborv1.PostgresSQL) *CredentialRotationReconciler {
+	return &CredentialRotationReconciler{
+		PostgreSQLReconciler: PostgreSQLReconciler{
+			HarborCluster: &goharborv1.HarborCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "harbor",
+					Namespace:         "default",
+					Annotations:       annotations,
+					CreationTimestamp: metav1.NewTime(time.Now().Add(-48 * time.Hour)),
+				},
+				Spec: goharborv1.HarborClusterSpec{
+					Database: &goharborv1.Database{Kind: goharborv1.InClusterComponent, Spec: spec},
+				},
+			},
+		},
+	}
+}
+
+func TestGetRequestedRotationID(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		component   string
+		status      *goharborv1.DatabaseCredentialStatus
+		wantID      string
+		wantOK      bool
+	}{
+		{
+			name:      "rotation is not requested",
+			component: HarborCore,
+			status:    &goharborv1.DatabaseCredentialStatus{Component: HarborCore},
+		},
+		{
+			name:        "rotation is requested for all components",
+			annotations: map[string]string{goharborv1.DatabaseCredentialRotationAnnotation: "r1"},
+			component:   HarborClair,
+			status:      &goharborv1.DatabaseCredentialStatus{Component: HarborClair},
+			wantID:      "r1",
+			wantOK:      true,
+		},
+		{
+			name:        "rotation is already handled",
+			annotations: map[string]string{goharborv1.DatabaseCredentialRotationAnnotation: "r1"},
+			component:   HarborCore,
+			status:      &goharborv1.DatabaseCredentialStatus{Component: HarborCore, RotationID: "r1"},
+		},
+		{
+			name:        "new rotation after a handled one",
+			annotations: map[string]string{goharborv1.DatabaseCredentialRotationAnnotation: "r2"},
+			component:   HarborCore,
+			status:      &goharborv1.DatabaseCredentialStatus{Component: HarborCore, RotationID: "r1"},
+			wantID:      "r2",
+			wantOK:      true,
+		},
+		{
+			name: "component is selected",
+			annotations: map[string]string{
+				goharborv1.DatabaseCredentialRotationAnnotation:           "r1",
+				goharborv1.DatabaseCredentialRotationComponentsAnnotation: "core, notaryServer",
+			},
+			component: HarborNotaryServer,
+			status:    &goharborv1.DatabaseCredentialStatus{Component: HarborNotaryServer},
+			wantID:    "r1",
+			wantOK:    true,
+		},
+		{
+			name: "component is not selected",
+			annotations: map[string]string{
+				goharborv1.DatabaseCredentialRotationAnnotation:           "r1",
+				goharborv1.DatabaseCredentialRotationComponentsAnnotation: "core,notaryServer",
+			},
+			component: HarborClair,
+			status:    &goharborv1.DatabaseCredentialStatus{Component: HarborClair},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rotation := newRotationReconciler(c.annotations, nil)
+			id, ok := rotation.getRequestedRotationID(c.component, c.status)
+			if id != c.wantID || ok != c.wantOK {
+				t.Errorf("getRequestedRotationID() = (%q, %v), want (%q, %v)", id, ok, c.wantID, c.wantOK)
+			}
+		})
+	}
+}
+
+func TestIsRotationDue(t *testing.T) {
+	interval := &goharborv1.PostgresSQL{
+		CredentialRotation: &goharborv1.DatabaseCredentialRotation{Interval: metav1.Duration{Duration: 24 * time.Hour}},
+	}
+	ago := func(d time.Duration) *metav1.Time {
+		t := metav1.NewTime(time.Now().Add(-d))
+		return &t
+	}
+
+	cases := []struct {
+		name   string
+		spec   *goharborv1.PostgresSQL
+		status *goharborv1.DatabaseCredentialStatus
+		want   bool
+	}{
+		{
+			name:   "database spec is not set",
+			status: &goharborv1.DatabaseCredentialStatus{},
+		},
+		{
+			name:   "scheduled rotation is disabled",
+			spec:   &goharborv1.PostgresSQL{},
+			status: &goharborv1.DatabaseCredentialStatus{},
+		},
+		{
+			name:   "never rotated since the cluster is created",
+			spec:   interval,
+			status: &goharborv1.DatabaseCredentialStatus{},
+			want:   true,
+		},
+		{
+			name:   "rotation is rolling",
+			spec:   interval,
+			status: &goharborv1.DatabaseCredentialStatus{Phase: RotationPhaseRolling, StartTime: ago(72 * time.Hour)},
+		},
+		{
+			name:   "completed within the interval",
+			spec:   interval,
+			status: &goharborv1.DatabaseCredentialStatus{Phase: RotationPhaseCompleted, LastRotationTime: ago(time.Hour)},
+		},
+		{
+			name:   "completed before the interval",
+			spec:   interval,
+			status: &goharborv1.DatabaseCredentialStatus{Phase: RotationPhaseCompleted, LastRotationTime: ago(25 * time.Hour)},
+			want:   true,
+		},
+		{
+			name: "failed within the interval",
+			spec: interval,
+			status: &goharborv1.DatabaseCredentialStatus{
+				Phase:            RotationPhaseFailed,
+				StartTime:        ago(time.Hour),
+				LastRotationTime: ago(72 * time.Hour),
+			},
+		},
+		{
+			name: "failed before the interval is retried",
+			spec: interval,
+			status: &goharborv1.DatabaseCredentialStatus{
+				Phase:            RotationPhaseFailed,
+				StartTime:        ago(25 * time.Hour),
+				LastRotationTime: ago(72 * time.Hour),
+			},
+			want: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rotation := newRotationReconciler(nil, c.spec)
+			if due := rotation.isRotationDue(c.status); due != c.want {
+				t.Errorf("isRotationDue() = %v, want %v", due, c.want)
+			}
+		})
+	}
+}
+
+func TestMergeStatuses(t *testing.T) {
+	cases := []struct {
+		name     string
+		previous []goharborv1.DatabaseCredentialStatus
+		handled  []goharborv1.DatabaseCredentialStatus
+		want     []goharborv1.DatabaseCredentialStatus
+	}{
+		{
+			name: "no previous statuses",
+			handled: []goharborv1.DatabaseCredentialStatus{
+				{Component: HarborCore, Phase: RotationPhaseRolling},
+			},
+			want: []goharborv1.DatabaseCredentialStatus{
+				{Component: HarborCore, Phase: RotationPhaseRolling},
+			},
+		},
+		{
+			name: "handled statuses replace the previous ones",
+			previous: []goharborv1.DatabaseCredentialStatus{
+				{Component: HarborCore, Phase: RotationPhaseRolling},
+			},
+			handled: []goharborv1.DatabaseCredentialStatus{
+				{Component: HarborCore, Phase: RotationPhaseCompleted},
+			},
+			want: []goharborv1.DatabaseCredentialStatus{
+				{Component: HarborCore, Phase: RotationPhaseCompleted},
+			},
+		},
+		{
+			name: "statuses of components not handled are kept",
+			previous: []goharborv1.DatabaseCredentialStatus{
+				{Component: HarborCore, Phase: RotationPhaseCompleted},
+				{Component: HarborClair, User: "clair_alt", Phase: RotationPhaseCompleted},
+			},
+			handled: []goharborv1.DatabaseCredentialStatus{
+				{Component: HarborCore, Phase: RotationPhaseFailed},
+			},
+			want: []goharborv1.DatabaseCredentialStatus{
+				{Component: HarborCore, Phase: RotationPhaseFailed},
+				{Component: HarborClair, User: "clair_alt", Phase: RotationPhaseCompleted},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rotation := newRotationReconciler(nil, nil)
+			rotation.HarborCluster.Status.DatabaseCredentials = c.previous
+			if got := rotation.mergeStatuses(c.handled); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("mergeStatuses() = %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestIsDeploymentUsingSecret(t *testing.T) {
+	withPodSpec := func(spec corev1.PodSpec) *appsv1.Deployment {
+		return &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: spec}}}
+	}
+	envFrom := func(secretName string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: "POSTGRESQL_PASSWORD",
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  "password",
+			}},
+		}
+	}
+
+	cases := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		secretName string
+		want       bool
+	}{
+		{
+			name:       "secret is mounted as volume",
+			deployment: withPodSpec(corev1.PodSpec{Volumes: []corev1.Volume{{Name: "database", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "core-database"}}}}}),
+			secretName: "core-database",
+			want:       true,
+		},
+		{
+			name:       "secret is read by env",
+			deployment: withPodSpec(corev1.PodSpec{Containers: []corev1.Container{{Name: "core", Env: []corev1.EnvVar{{Name: "PORT", Value: "8080"}, envFrom("core-database-alt")}}}}),
+			secretName: "core-database-alt",
+			want:       true,
+		},
+		{
+			name:       "deployment still reads the previous secret",
+			deployment: withPodSpec(corev1.PodSpec{Containers: []corev1.Container{{Name: "core", Env: []corev1.EnvVar{envFrom("core-database")}}}}),
+			secretName: "core-database-alt",
+		},
+		{
+			name:       "deployment reads no secret",
+			deployment: withPodSpec(corev1.PodSpec{Containers: []corev1.Container{{Name: "core"}}}),
+			secretName: "core-database",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isDeploymentUsingSecret(c.deployment, c.secretName); got != c.want {
+				t.Errorf("isDeploymentUsingSecret() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
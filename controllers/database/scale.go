@@ -0,0 +1,288 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/controllers/database/api"
+	"github.com/goharbor/harbor-cluster-operator/lcm"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	labels1 "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// MinHADatabaseReplicas is the number of instances to keep a replica to fail over
+	MinHADatabaseReplicas = 2
+
+	PatroniAPIPort    = 8008
+	PatroniAPITimeout = 10 * time.Second
+)
+
+// IsScalingEvent returns whether the instances or volume size of inCluster database in spec
+// differ from the postgresql CR.
+func (postgres *PostgreSQLReconciler) IsScalingEvent() (bool, error) {
+	actualCR, expectCR, err := postgres.getPostgresqls()
+	if err != nil {
+		return false, err
+	}
+
+	return actualCR.Spec.NumberOfInstances != expectCR.Spec.NumberOfInstances ||
+		actualCR.Spec.Volume.Size != expectCR.Spec.Volume.Size, nil
+}
+
+// Scale reconcile will scale the inCluster database to the instances and volume size in spec.
+// It does:
+// - refuse to scale below one instance, and warn when scaling below two instances
+// - reject shrinking the volume, which can not be done by postgres operator
+// - expand the volumes online if the storage class allows volume expansion
+// - scale up the instances to the desired number
+// - scale down one instance at a time, and switch over first if the removed instance is leader
+func (postgres *PostgreSQLReconciler) Scale() (*lcm.CRStatus, error) {
+	actualCR, expectCR, err := postgres.getPostgresqls()
+	if err != nil {
+		return databaseNotReadyStatus(DefaultUnstructuredConverterError, err.Error()), err
+	}
+
+	// the same rule as the webhook, in case the spec is admitted without it
+	desired := expectCR.Spec.NumberOfInstances
+	if desired < goharborv1.MinDatabaseReplicas {
+		err := fmt.Errorf("database instances %d must not be less than %d", desired, goharborv1.MinDatabaseReplicas)
+		return databaseNotReadyStatus(DatabaseReplicasBelowMinimumError, err.Error()), err
+	}
+
+	if actualCR.Spec.Volume.Size != expectCR.Spec.Volume.Size {
+		return postgres.ExpandVolume(actualCR.Spec.Volume.Size, expectCR.Spec.Volume.Size)
+	}
+
+	current := actualCR.Spec.NumberOfInstances
+	if desired < MinHADatabaseReplicas && current >= MinHADatabaseReplicas {
+		postgres.Recorder.Event(postgres.HarborCluster, corev1.EventTypeWarning, DatabaseNotHighlyAvailable,
+			fmt.Sprintf(MessageDatabaseNotHighlyAvailable, desired))
+	}
+
+	if desired > current {
+		return postgres.ScaleUp(uint64(desired))
+	}
+	return postgres.ScaleDown(uint64(desired))
+}
+
+// ScaleUp scales up the inCluster database to the desired instances at once
+func (postgres *PostgreSQLReconciler) ScaleUp(newReplicas uint64) (*lcm.CRStatus, error) {
+	current, _, err := unstructured.NestedInt64(postgres.ActualCR.Object, "spec", "numberOfInstances")
+	if err != nil {
+		return databaseNotReadyStatus(DefaultUnstructuredConverterError, err.Error()), err
+	}
+
+	if err := postgres.updateScale(int64(newReplicas), "spec", "numberOfInstances"); err != nil {
+		return databaseNotReadyStatus(UpdateDatabaseCrError, err.Error()), err
+	}
+
+	msg := fmt.Sprintf(MessageDatabaseUpScaling, current, newReplicas)
+	postgres.Recorder.Event(postgres.HarborCluster, corev1.EventTypeNormal, UpScalingDatabase, msg)
+
+	return databaseUnknownStatus(), nil
+}
+
+// ScaleDown removes one instance of the inCluster database at a time until the desired instances.
+// The instance with the highest ordinal is removed by postgres operator, the leader is switched over
+// to another instance first if it is the removed one.
+func (postgres *PostgreSQLReconciler) ScaleDown(newReplicas uint64) (*lcm.CRStatus, error) {
+	current, _, err := unstructured.NestedInt64(postgres.ActualCR.Object, "spec", "numberOfInstances")
+	if err != nil {
+		return databaseNotReadyStatus(DefaultUnstructuredConverterError, err.Error()), err
+	}
+
+	masters, replicas, err := postgres.GetSpiloPods()
+	if err != nil {
+		return databaseNotReadyStatus(GetDatabasePodError, err.Error()), err
+	}
+	if len(masters) != 1 || int64(len(masters)+len(replicas)) != current {
+		return databaseUnknownStatus(), nil
+	}
+
+	next := current - 1
+	removedPod := fmt.Sprintf("%s-%d", postgres.GetDatabaseName(), next)
+	if masters[0].Name == removedPod {
+		if len(replicas) == 0 {
+			return databaseUnknownStatus(), nil
+		}
+		postgres.Log.Info("Database pod to be removed is leader, switch over first.",
+			"namespace", postgres.HarborCluster.Namespace, "pod", removedPod)
+		if err := postgres.switchover(&masters[0], replicas[0].Name); err != nil {
+			return databaseNotReadyStatus(SwitchoverDatabaseError, err.Error()), err
+		}
+		return databaseUnknownStatus(), nil
+	}
+
+	postgres.Log.Info("Scale down database.",
+		"namespace", postgres.HarborCluster.Namespace, "name", postgres.GetDatabaseName(),
+		"from", current, "to", next, "desired", newReplicas)
+
+	if err := postgres.updateScale(next, "spec", "numberOfInstances"); err != nil {
+		return databaseNotReadyStatus(UpdateDatabaseCrError, err.Error()), err
+	}
+
+	msg := fmt.Sprintf(MessageDatabaseDownScaling, current, next)
+	postgres.Recorder.Event(postgres.HarborCluster, corev1.EventTypeNormal, DownScalingDatabase, msg)
+
+	return databaseUnknownStatus(), nil
+}
+
+// ExpandVolume expands the volumes of inCluster database online.
+// The persistent volume claims are expanded directly, so that it does not depend on the
+// resize mode of postgres operator.
+func (postgres *PostgreSQLReconciler) ExpandVolume(currentSize, desiredSize string) (*lcm.CRStatus, error) {
+	current, err := resource.ParseQuantity(currentSize)
+	if err != nil {
+		return databaseNotReadyStatus(DatabaseVolumeSizeError, err.Error()), err
+	}
+	desired, err := resource.ParseQuantity(desiredSize)
+	if err != nil {
+		return databaseNotReadyStatus(DatabaseVolumeSizeError, err.Error()), err
+	}
+
+	if desired.Cmp(current) < 0 {
+		return databaseNotReadyStatus(DatabaseVolumeShrinkError,
+			fmt.Sprintf("database volume can not be shrunk from %s to %s", currentSize, desiredSize)), nil
+	}
+
+	pvcs, err := postgres.getSpiloVolumeClaims()
+	if err != nil {
+		return databaseNotReadyStatus(GetDatabaseVolumeError, err.Error()), err
+	}
+
+	for i := range pvcs {
+		expandable, err := postgres.isVolumeExpandable(&pvcs[i])
+		if err != nil {
+			return databaseNotReadyStatus(GetDatabaseVolumeError, err.Error()), err
+		}
+		if !expandable {
+			return databaseNotReadyStatus(DatabaseVolumeExpansionError,
+				fmt.Sprintf("storage class of volume %s does not allow volume expansion", pvcs[i].Name)), nil
+		}
+	}
+
+	for i := range pvcs {
+		size := pvcs[i].Spec.Resources.Requests[corev1.ResourceStorage]
+		if size.Cmp(desired) >= 0 {
+			continue
+		}
+
+		postgres.Log.Info("Expand database volume.",
+			"namespace", pvcs[i].Namespace, "name", pvcs[i].Name, "from", size.String(), "to", desiredSize)
+
+		pvcs[i].Spec.Resources.Requests[corev1.ResourceStorage] = desired
+		if err := postgres.Client.Update(&pvcs[i]); err != nil {
+			return databaseNotReadyStatus(ExpandDatabaseVolumeError, err.Error()), err
+		}
+	}
+
+	if err := postgres.updateScale(desiredSize, "spec", "volume", "size"); err != nil {
+		return databaseNotReadyStatus(UpdateDatabaseCrError, err.Error()), err
+	}
+
+	msg := fmt.Sprintf(MessageDatabaseVolumeExpanding, currentSize, desiredSize)
+	postgres.Recorder.Event(postgres.HarborCluster, corev1.EventTypeNormal, ExpandingDatabaseVolume, msg)
+
+	return databaseUnknownStatus(), nil
+}
+
+// getSpiloVolumeClaims returns the persistent volume claims of inCluster database
+func (postgres *PostgreSQLReconciler) getSpiloVolumeClaims() ([]corev1.PersistentVolumeClaim, error) {
+	opts := &client.ListOptions{
+		Namespace: postgres.HarborCluster.Namespace,
+		LabelSelector: labels1.SelectorFromSet(map[string]string{
+			"application":  "spilo",
+			"cluster-name": postgres.GetDatabaseName(),
+		}),
+	}
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := postgres.Client.List(opts, pvcs); err != nil {
+		return nil, err
+	}
+	return pvcs.Items, nil
+}
+
+// isVolumeExpandable returns whether the storage class of the persistent volume claim allows volume expansion
+func (postgres *PostgreSQLReconciler) isVolumeExpandable(pvc *corev1.PersistentVolumeClaim) (bool, error) {
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return false, nil
+	}
+
+	sc := &storagev1.StorageClass{}
+	if err := postgres.Client.Get(types.NamespacedName{Name: *pvc.Spec.StorageClassName}, sc); err != nil {
+		return false, err
+	}
+	return sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion, nil
+}
+
+// switchover asks Patroni to switch over the leader to the candidate
+func (postgres *PostgreSQLReconciler) switchover(leader *corev1.Pod, candidate string) error {
+	body, err := json.Marshal(map[string]string{
+		"leader":    leader.Name,
+		"candidate": candidate,
+	})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("http://%s:%d/switchover", leader.Status.PodIP, PatroniAPIPort)
+	req, err := http.NewRequestWithContext(postgres.Ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{Timeout: PatroniAPITimeout}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("patroni switchover from %s to %s failed with status %d", leader.Name, candidate, resp.StatusCode)
+	}
+	return nil
+}
+
+// updateScale sets the field of actual postgresql CR and updates it,
+// the fields not known by the operator are kept.
+func (postgres *PostgreSQLReconciler) updateScale(value interface{}, fields ...string) error {
+	actualCR := postgres.ActualCR.DeepCopy()
+	if err := unstructured.SetNestedField(actualCR.Object, value, fields...); err != nil {
+		return err
+	}
+
+	crdClient := postgres.DClient.WithResource(databaseFailoversGVR).WithNamespace(postgres.HarborCluster.Namespace)
+	_, err := crdClient.Update(actualCR, metav1.UpdateOptions{})
+	return err
+}
+
+// getPostgresqls returns the actual and expect postgresql CR
+func (postgres *PostgreSQLReconciler) getPostgresqls() (*api.Postgresql, *api.Postgresql, error) {
+	var actualCR api.Postgresql
+	var expectCR api.Postgresql
+
+	if err := runtime.DefaultUnstructuredConverter.
+		FromUnstructured(postgres.ActualCR.UnstructuredContent(), &actualCR); err != nil {
+		return nil, nil, err
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.
+		FromUnstructured(postgres.ExpectCR.UnstructuredContent(), &expectCR); err != nil {
+		return nil, nil, err
+	}
+
+	return &actualCR, &expectCR, nil
+}
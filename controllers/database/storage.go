@@ -0,0 +1,55 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/lcm"
+)
+
+// getStorageConfig returns the s3 config in the storage secret of harbor cluster
+func (postgres *PostgreSQLReconciler) getStorageConfig(secretName string) (map[string]string, error) {
+	data, err := postgres.GetSecret(secretName)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, ok := data["s3"]
+	if !ok {
+		return nil, fmt.Errorf("secret %s is not a s3 storage secret", secretName)
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	config := map[string]string{}
+	for k, v := range fields {
+		config[k] = fmt.Sprintf("%v", v)
+	}
+	return config, nil
+}
+
+func (postgres *PostgreSQLReconciler) getStorageProperty() *lcm.Property {
+	storage, ok := postgres.ComponentToCRStatus[goharborv1.ComponentStorage]
+	if !ok || storage == nil {
+		return nil
+	}
+
+	if p := storage.Properties.Get(lcm.InClusterSecretForStorage); p != nil {
+		return p
+	}
+	return storage.Properties.Get(lcm.S3SecretForStorage)
+}
+
+// getStorageSecretName returns the storage secret which is S3 compatible
+func (postgres *PostgreSQLReconciler) getStorageSecretName() (string, error) {
+	p := postgres.getStorageProperty()
+	if p == nil {
+		return "", errors.New("the S3 compatible storage is not ready")
+	}
+	return p.ToString(), nil
+}
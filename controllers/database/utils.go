@@ -1,9 +1,13 @@
 package database
 
 import (
+	"fmt"
+
 	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/controllers/common"
 	"github.com/goharbor/harbor-cluster-operator/controllers/database/api"
 	"github.com/goharbor/harbor-cluster-operator/lcm"
+	"github.com/jackc/pgx/v4"
 
 	//pg "github.com/zalando/postgres-operator/pkg/apis/acid.zalan.do/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -14,25 +18,152 @@ const (
 	DefaultDatabaseReplica = 3
 	DefaultDatabaseMemory  = "1Gi"
 	DefaultDatabaseVersion = "12"
+	DatabasePasswordLength = 32
+)
+
+var (
+	// componentDatabases maps harbor components to their databases
+	componentDatabases = map[string]string{
+		HarborCore:         CoreDatabase,
+		HarborClair:        ClairDatabase,
+		HarborNotaryServer: NotaryServerDatabase,
+		HarborNotarySigner: NotarySignerDatabase,
+	}
+
+	// componentSecretNames maps harbor components to the prefix of their database secrets
+	componentSecretNames = map[string]string{
+		HarborCore:         CoreSecretName,
+		HarborClair:        ClairSecretName,
+		HarborNotaryServer: NotaryServerSecretName,
+		HarborNotarySigner: NotarySignerSecretName,
+	}
 )
 
+// GetDatabases returns the databases of harbor components and their owners,
+// each component owns its database with a user named after the database.
 func (postgres *PostgreSQLReconciler) GetDatabases() map[string]string {
 	databases := map[string]string{
-		CoreDatabase: "zalando",
+		CoreDatabase: CoreDatabase,
 	}
 
 	if postgres.HarborCluster.Spec.Clair != nil {
-		databases[ClairDatabase] = "zalando"
+		databases[ClairDatabase] = ClairDatabase
 	}
 
 	if postgres.HarborCluster.Spec.Notary != nil {
-		databases[NotaryServerDatabase] = "zalando"
-		databases[NotarySignerDatabase] = "zalando"
+		databases[NotaryServerDatabase] = NotaryServerDatabase
+		databases[NotarySignerDatabase] = NotarySignerDatabase
 	}
 
 	return databases
 }
 
+// GetUsers returns the database users of harbor components, the users have no extra privilege.
+func (postgres *PostgreSQLReconciler) GetUsers() map[string]api.UserFlags {
+	users := map[string]api.UserFlags{}
+	for _, owner := range postgres.GetDatabases() {
+		users[owner] = api.UserFlags{}
+	}
+	return users
+}
+
+// getActiveUser returns the login role used by the component, which is switched by credential rotation.
+// It is the database owner by default.
+func (postgres *PostgreSQLReconciler) getActiveUser(component string) string {
+	for _, status := range postgres.HarborCluster.Status.DatabaseCredentials {
+		if status.Component == component && status.User != "" {
+			return status.User
+		}
+	}
+	return componentDatabases[component]
+}
+
+// getStandbyUser returns the login role of the component which is not the given one
+func getStandbyUser(component, username string) string {
+	owner := componentDatabases[component]
+	if username == owner {
+		return owner + AlternateUserSuffix
+	}
+	return owner
+}
+
+// getUserSecretName returns the component secret of the login role
+func getUserSecretName(component, username string) string {
+	secretName := getComponentSecretName(componentSecretNames[component])
+	if username == componentDatabases[component] {
+		return secretName
+	}
+	return fmt.Sprintf("%s-%s", secretName, AlternateSecretSuffix)
+}
+
+// GetComponentConn returns the connection of harbor component with its own database user.
+// For inCluster database the password is generated by postgres operator,
+// for external database the password is set by the admin connection only if the component can not connect with it.
+// The objects created by the shared user before are transferred to the component user once.
+func (postgres *PostgreSQLReconciler) GetComponentConn(client *pgx.Conn, admin *Connect, component, secretName string) (*Connect, error) {
+	database := componentDatabases[component]
+
+	conn := *admin
+	conn.Database = database
+	conn.Username = database
+
+	switch postgres.HarborCluster.Spec.Database.Kind {
+	case goharborv1.InClusterComponent:
+		pw, err := postgres.GetInClusterUserPassword(conn.Username)
+		if err != nil {
+			return nil, err
+		}
+		conn.Password = pw
+		if postgres.IsConnectionPoolerEnabled() {
+			conn.Host = postgres.GetConnectionPoolerHost()
+		}
+	case goharborv1.ExternalComponent:
+		conn.Password = postgres.getComponentPassword(secretName, conn.Username)
+		if err := postgres.EnsureLogin(client, &conn); err != nil {
+			return nil, err
+		}
+	}
+
+	if !postgres.isOwnershipMigrated(database) {
+		if err := postgres.MigrateOwnership(admin, database, database, postgres.getLegacyOwners(admin)); err != nil {
+			return nil, err
+		}
+		postgres.HarborCluster.Status.DatabaseOwnershipMigrated = append(postgres.HarborCluster.Status.DatabaseOwnershipMigrated, database)
+	}
+
+	return &conn, nil
+}
+
+// isOwnershipMigrated returns whether the objects of the database have been transferred to the component user,
+// the objects created by the admin afterwards are not transferred again.
+func (postgres *PostgreSQLReconciler) isOwnershipMigrated(database string) bool {
+	for _, migrated := range postgres.HarborCluster.Status.DatabaseOwnershipMigrated {
+		if migrated == database {
+			return true
+		}
+	}
+	return false
+}
+
+// getLegacyOwners returns the roles owning the objects of harbor before each component has its own user.
+// The inCluster databases were owned by zalando and used by the admin, the external databases were used by the admin.
+func (postgres *PostgreSQLReconciler) getLegacyOwners(admin *Connect) []string {
+	if postgres.HarborCluster.Spec.Database.Kind == goharborv1.InClusterComponent {
+		return []string{LegacyDatabaseOwner, admin.Username}
+	}
+	return []string{admin.Username}
+}
+
+// getComponentPassword returns the password in the component secret if it belongs to the user,
+// otherwise a new password is generated.
+func (postgres *PostgreSQLReconciler) getComponentPassword(secretName, username string) string {
+	secret, err := postgres.GetSecret(secretName)
+	if err == nil && string(secret["username"]) == username && len(secret["password"]) > 0 {
+		return string(secret["password"])
+	}
+	return common.RandomString(DatabasePasswordLength, common.LowerStringRandomType)
+}
+
 // GetDatabaseConn is getting database connection
 func (postgres *PostgreSQLReconciler) GetDatabaseConn(secretName string) (*Connect, error) {
 	secret, err := postgres.GetSecret(secretName)
@@ -48,9 +179,48 @@ func (postgres *PostgreSQLReconciler) GetDatabaseConn(secretName string) (*Conne
 		Database: string(secret["database"]),
 	}
 
+	if err := postgres.SetConnectSSL(conn); err != nil {
+		return nil, err
+	}
+
 	return conn, nil
 }
 
+// SetConnectSSL sets the ssl mode, certificates and connect timeout of spec to the connection
+func (postgres *PostgreSQLReconciler) SetConnectSSL(conn *Connect) error {
+	spec := postgres.HarborCluster.Spec.Database.Spec
+	if spec == nil {
+		return nil
+	}
+
+	conn.SSLMode = spec.SslMode
+	conn.ConnectTimeout = spec.ConnectTimeout
+
+	if spec.SslConfig == "" {
+		return nil
+	}
+
+	secret, err := postgres.GetSecret(spec.SslConfig)
+	if err != nil {
+		return err
+	}
+
+	conn.CACert = secret[SSLCAKey]
+	conn.ClientCert = secret[SSLCertKey]
+	conn.ClientKey = secret[SSLKeyKey]
+
+	return nil
+}
+
+// GetSSLMode returns the ssl mode of spec, default is disable
+func (postgres *PostgreSQLReconciler) GetSSLMode() string {
+	conn := &Connect{}
+	if spec := postgres.HarborCluster.Spec.Database.Spec; spec != nil {
+		conn.SSLMode = spec.SslMode
+	}
+	return conn.GetSSLMode()
+}
+
 // GetSecret returns the database connection Secret
 func (postgres *PostgreSQLReconciler) GetSecret(secretName string) (map[string][]byte, error) {
 	secret := &corev1.Secret{}
@@ -130,6 +300,66 @@ func (postgres *PostgreSQLReconciler) GetPostgreVersion() string {
 	return postgres.HarborCluster.Spec.Database.Spec.Version
 }
 
+// GetPostgreParameters returns the PostgreSQL parameters of spec
+func (postgres *PostgreSQLReconciler) GetPostgreParameters() map[string]string {
+	if postgres.HarborCluster.Spec.Database.Spec == nil || len(postgres.HarborCluster.Spec.Database.Spec.Parameters) == 0 {
+		return nil
+	}
+
+	parameters := make(map[string]string, len(postgres.HarborCluster.Spec.Database.Spec.Parameters))
+	for k, v := range postgres.HarborCluster.Spec.Database.Spec.Parameters {
+		parameters[k] = v
+	}
+	return parameters
+}
+
+// GetPostgrePatroni returns the Patroni settings, the pg_hba entries of spec replace the default entries
+func (postgres *PostgreSQLReconciler) GetPostgrePatroni() api.Patroni {
+	patroni := api.Patroni{
+		InitDB: map[string]string{
+			"encoding":       "UTF8",
+			"locale":         "en_US.UTF-8",
+			"data-checksums": "true",
+		},
+		PgHba: []string{
+			"hostssl all all 0.0.0.0/0 md5",
+			"host    all all 0.0.0.0/0 md5",
+		},
+	}
+
+	spec := postgres.HarborCluster.Spec.Database.Spec
+	if spec == nil {
+		return patroni
+	}
+
+	if len(spec.PgHba) > 0 {
+		patroni.PgHba = append([]string{}, spec.PgHba...)
+	}
+
+	if spec.Patroni != nil {
+		patroni.TTL = spec.Patroni.TTL
+		patroni.LoopWait = spec.Patroni.LoopWait
+		patroni.RetryTimeout = spec.Patroni.RetryTimeout
+		if spec.Patroni.MaximumLagOnFailover != nil {
+			patroni.MaximumLagOnFailover = float32(*spec.Patroni.MaximumLagOnFailover)
+		}
+	}
+
+	return patroni
+}
+
+// getResourceDescription returns the cpu and memory of postgres operator from the resource list
+func getResourceDescription(list corev1.ResourceList) api.ResourceDescription {
+	description := api.ResourceDescription{}
+	if cpu, ok := list[corev1.ResourceCPU]; ok {
+		description.CPU = cpu.String()
+	}
+	if mem, ok := list[corev1.ResourceMemory]; ok {
+		description.Memory = mem.String()
+	}
+	return description
+}
+
 func databaseNotReadyStatus(reason, message string) *lcm.CRStatus {
 	return lcm.New(goharborv1.DatabaseReady).
 		WithStatus(corev1.ConditionFalse).
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestGetStandbyUser(t *testing.T) {
+	cases := []struct {
+		name      string
+		component string
+		username  string
+		want      string
+	}{
+		{
+			name:      "owner switches to the alternate role",
+			component: HarborCore,
+			username:  CoreDatabase,
+			want:      CoreDatabase + AlternateUserSuffix,
+		},
+		{
+			name:      "alternate role switches back to the owner",
+			component: HarborCore,
+			username:  CoreDatabase + AlternateUserSuffix,
+			want:      CoreDatabase,
+		},
+		{
+			name:      "alternate role of notary signer",
+			component: HarborNotarySigner,
+			username:  NotarySignerDatabase + AlternateUserSuffix,
+			want:      NotarySignerDatabase,
+		},
+		{
+			name:      "owner of notary server",
+			component: HarborNotaryServer,
+			username:  NotaryServerDatabase,
+			want:      NotaryServerDatabase + AlternateUserSuffix,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := getStandbyUser(c.component, c.username); got != c.want {
+				t.Errorf("getStandbyUser(%q, %q) = %q, want %q", c.component, c.username, got, c.want)
+			}
+		})
+	}
+}
+
+func TestGetUserSecretName(t *testing.T) {
+	cases := []struct {
+		name      string
+		component string
+		username  string
+		want      string
+	}{
+		{
+			name:      "owner uses the component secret",
+			component: HarborCore,
+			username:  CoreDatabase,
+			want:      "core-database",
+		},
+		{
+			name:      "alternate role uses the alternate secret",
+			component: HarborCore,
+			username:  CoreDatabase + AlternateUserSuffix,
+			want:      "core-database-alt",
+		},
+		{
+			name:      "owner of clair",
+			component: HarborClair,
+			username:  ClairDatabase,
+			want:      "clair-database",
+		},
+		{
+			name:      "alternate role of notary signer",
+			component: HarborNotarySigner,
+			username:  getStandbyUser(HarborNotarySigner, NotarySignerDatabase),
+			want:      "notary-signer-database-alt",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := getUserSecretName(c.component, c.username); got != c.want {
+				t.Errorf("getUserSecretName(%q, %q) = %q, want %q", c.component, c.username, got, c.want)
+			}
+		})
+	}
+}
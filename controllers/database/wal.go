@@ -0,0 +1,174 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/goharbor/harbor-cluster-operator/controllers/database/api"
+	corev1 "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	DefaultWalBackupSchedule  = "30 00 * * *"
+	DefaultWalBackupRetention = 5
+
+	WalAccessKeyIDKey     = "AWS_ACCESS_KEY_ID"
+	WalSecretAccessKeyKey = "AWS_SECRET_ACCESS_KEY"
+)
+
+// IsWalStorageRequired returns whether the inCluster database archives WAL to or clones from the storage
+func (postgres *PostgreSQLReconciler) IsWalStorageRequired() bool {
+	spec := postgres.HarborCluster.Spec.Database.Spec
+	if spec == nil {
+		return false
+	}
+	return (spec.WalArchive != nil && spec.WalArchive.Enabled) || spec.Clone != nil
+}
+
+// DeployWalSecret deploys the secret of storage credentials used by spilo to archive and fetch WAL
+func (postgres *PostgreSQLReconciler) DeployWalSecret() error {
+	config, err := postgres.getWalStorageConfig()
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      postgres.getWalSecretName(),
+			Namespace: postgres.HarborCluster.Namespace,
+			Labels:    postgres.Labels,
+		},
+		StringData: map[string]string{
+			WalAccessKeyIDKey:     config["accesskey"],
+			WalSecretAccessKeyKey: config["secretkey"],
+		},
+	}
+	if err := controllerutil.SetControllerReference(postgres.HarborCluster, secret, postgres.Scheme); err != nil {
+		return err
+	}
+
+	actual := &corev1.Secret{}
+	err = postgres.Client.Get(types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, actual)
+	if kerr.IsNotFound(err) {
+		postgres.Log.Info("Creating database WAL secret.",
+			"namespace", secret.Namespace, "name", secret.Name)
+		return postgres.Client.Create(secret)
+	} else if err != nil {
+		return err
+	}
+
+	if isSecretDataEqual(actual.Data, secret.StringData) {
+		return nil
+	}
+	actual.Data = nil
+	actual.StringData = secret.StringData
+	return postgres.Client.Update(actual)
+}
+
+// GenWalEnv returns the spilo environments to archive WAL and base backups with WAL-G,
+// the WAL of cluster is kept in "s3://<bucket>/spilo/<cluster>/wal/<version>".
+func (postgres *PostgreSQLReconciler) GenWalEnv(config map[string]string) []corev1.EnvVar {
+	spec := postgres.HarborCluster.Spec.Database.Spec
+
+	env := []corev1.EnvVar{
+		postgres.genWalSecretEnv(WalAccessKeyIDKey, WalAccessKeyIDKey),
+		postgres.genWalSecretEnv(WalSecretAccessKeyKey, WalSecretAccessKeyKey),
+		{Name: "AWS_REGION", Value: config["region"]},
+		{Name: "WAL_S3_BUCKET", Value: config["bucket"]},
+		{Name: "WAL_BUCKET_SCOPE_PREFIX", Value: ""},
+		{Name: "WAL_BUCKET_SCOPE_SUFFIX", Value: ""},
+		{Name: "USE_WALG_BACKUP", Value: strconv.FormatBool(spec.WalArchive != nil && spec.WalArchive.Enabled)},
+		{Name: "USE_WALG_RESTORE", Value: "true"},
+	}
+
+	if endpoint := config["regionendpoint"]; endpoint != "" {
+		env = append(env,
+			corev1.EnvVar{Name: "AWS_ENDPOINT", Value: endpoint},
+			corev1.EnvVar{Name: "AWS_S3_FORCE_PATH_STYLE", Value: "true"},
+			corev1.EnvVar{Name: "WALG_DISABLE_S3_SSE", Value: "true"},
+		)
+	}
+
+	if spec.WalArchive != nil && spec.WalArchive.Enabled {
+		schedule := spec.WalArchive.BackupSchedule
+		if schedule == "" {
+			schedule = DefaultWalBackupSchedule
+		}
+		retention := spec.WalArchive.BackupRetention
+		if retention <= 0 {
+			retention = DefaultWalBackupRetention
+		}
+		env = append(env,
+			corev1.EnvVar{Name: "BACKUP_SCHEDULE", Value: schedule},
+			corev1.EnvVar{Name: "BACKUP_NUM_TO_RETAIN", Value: strconv.Itoa(retention)},
+		)
+	}
+
+	if spec.Clone != nil {
+		env = append(env,
+			postgres.genWalSecretEnv("CLONE_"+WalAccessKeyIDKey, WalAccessKeyIDKey),
+			postgres.genWalSecretEnv("CLONE_"+WalSecretAccessKeyKey, WalSecretAccessKeyKey),
+			corev1.EnvVar{Name: "CLONE_AWS_REGION", Value: config["region"]},
+			corev1.EnvVar{Name: "CLONE_USE_WALG_RESTORE", Value: "true"},
+		)
+	}
+
+	return env
+}
+
+// GenCloneDescription returns the clone of postgresql CR, the source cluster is found by
+// the WAL path archived by the source HarborCluster.
+func (postgres *PostgreSQLReconciler) GenCloneDescription(config map[string]string) *api.CloneDescription {
+	clone := postgres.HarborCluster.Spec.Database.Spec.Clone
+	if clone == nil {
+		return nil
+	}
+
+	namespace := clone.Namespace
+	if namespace == "" {
+		namespace = postgres.HarborCluster.Namespace
+	}
+	cluster := fmt.Sprintf("%s-%s", namespace, clone.ClusterName)
+
+	description := &api.CloneDescription{
+		ClusterName:  cluster,
+		EndTimestamp: clone.Timestamp,
+		S3WalPath:    fmt.Sprintf("s3://%s/spilo/%s/wal/%s", config["bucket"], cluster, postgres.GetPostgreVersion()),
+		S3Endpoint:   config["regionendpoint"],
+	}
+	if description.S3Endpoint != "" {
+		forcePathStyle := true
+		description.S3ForcePathStyle = &forcePathStyle
+	}
+
+	return description
+}
+
+// getWalStorageConfig returns the s3 config of the storage which WAL is archived to
+func (postgres *PostgreSQLReconciler) getWalStorageConfig() (map[string]string, error) {
+	secretName, err := postgres.getStorageSecretName()
+	if err != nil {
+		return nil, err
+	}
+	return postgres.getStorageConfig(secretName)
+}
+
+func (postgres *PostgreSQLReconciler) genWalSecretEnv(name, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: postgres.getWalSecretName()},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+func (postgres *PostgreSQLReconciler) getWalSecretName() string {
+	return fmt.Sprintf("%s-database-wal", postgres.HarborCluster.Name)
+}
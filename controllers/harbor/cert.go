@@ -19,13 +19,17 @@ func (harbor *HarborReconciler) getRegistryIssuerNamespacedName() types.Namespac
 
 // CheckIssuer check issuer has exist, if not will create issuer
 func (harbor *HarborReconciler) CheckIssuer() error {
-	var issuer certv1.Issuer
-	namespacedName := harbor.getRegistryIssuerNamespacedName()
+	return EnsureIssuer(harbor.Client, harbor.getRegistryIssuerNamespacedName())
+}
 
-	err := harbor.Get(namespacedName, &issuer)
+// EnsureIssuer creates the self signed issuer if it does not exist, the issuer is shared by the components
+// whose certificates are issued before harbor is provisioned.
+func EnsureIssuer(client k8s.Client, namespacedName types.NamespacedName) error {
+	var issuer certv1.Issuer
+	err := client.Get(namespacedName, &issuer)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return provisionIssuer(harbor.Client, namespacedName.Name, namespacedName.Namespace)
+			return provisionIssuer(client, namespacedName.Name, namespacedName.Namespace)
 		}
 	}
 	return err
@@ -62,10 +62,18 @@ func (harbor *HarborReconciler) newHarborCR() *v1alpha1.Harbor {
 			AdminPasswordSecret:  harbor.HarborCluster.Spec.AdminPasswordSecret,
 			Priority:             harbor.HarborCluster.Spec.Priority,
 			CertificateIssuerRef: harbor.HarborCluster.Spec.CertificateIssuerRef,
+			// harbor is set read-only by the storage migration, it is lifted along with switching the storage secrets
+			ReadOnly: harbor.isStorageMigrating(),
 		},
 	}
 }
 
+// isStorageMigrating returns whether the objects are being migrated to another storage kind
+func (harbor *HarborReconciler) isStorageMigrating() bool {
+	migration := harbor.HarborCluster.Status.StorageMigration
+	return migration != nil && migration.CompletionTime == nil
+}
+
 func (harbor *HarborReconciler) newCoreComponent() *v1alpha1.CoreComponent {
 	return &v1alpha1.CoreComponent{
 		HarborDeployment: v1alpha1.HarborDeployment{
@@ -222,6 +230,8 @@ func (harbor *HarborReconciler) getStorageSecret() string {
 		name = lcm.S3SecretForStorage
 	case "oss":
 		name = lcm.OssSecretForStorage
+	case "filesystem":
+		name = lcm.FileSystemSecretForStorage
 	default:
 		// default in cluster storage has been provided.
 		name = lcm.InClusterSecretForStorage
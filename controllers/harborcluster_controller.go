@@ -24,8 +24,10 @@ import (
 
 	"github.com/goharbor/harbor-cluster-operator/controllers/image"
 	"github.com/goharbor/harbor-cluster-operator/controllers/k8s"
+	"github.com/goharbor/harbor-cluster-operator/controllers/storage"
 	"github.com/goharbor/harbor-cluster-operator/lcm"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
 
@@ -53,6 +55,20 @@ var (
 		goharborv1.ComponentCache:    goharborv1.CacheReady,
 		goharborv1.ComponentStorage:  goharborv1.StorageReady,
 		goharborv1.ComponentDatabase: goharborv1.DatabaseReady,
+
+		goharborv1.ComponentDatabaseBackup:  goharborv1.DatabaseBackupReady,
+		goharborv1.ComponentDatabaseRestore: goharborv1.DatabaseRestored,
+
+		goharborv1.ComponentDatabaseCredential: goharborv1.DatabaseCredentialRotated,
+
+		goharborv1.ComponentStorageUsage: goharborv1.StorageUsageWarning,
+	}
+	// NonBlockingConditionTypes are only reported in status, they do not block the reconciling of harbor.
+	NonBlockingConditionTypes = map[goharborv1.HarborClusterConditionType]bool{
+		goharborv1.ServiceReady:              true,
+		goharborv1.DatabaseBackupReady:       true,
+		goharborv1.DatabaseCredentialRotated: true,
+		goharborv1.StorageUsageWarning:       true,
 	}
 	ReconcileWaitResult = reconcile.Result{RequeueAfter: 30 * time.Second}
 )
@@ -63,10 +79,13 @@ var (
 // +kubebuilder:rbac:groups=databases.spotahome.com,resources=redisfailovers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=acid.zalan.do,resources=postgresqls,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=minio.min.io,resources=tenants,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=cert-manager.io,resources=issuers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cert-manager.io,resources=issuers;certificates,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=cronjobs;jobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=statefulsets;deployments,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups="",resources=pods;configmaps;services;events;secrets;ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods;configmaps;services;events;secrets;ingresses;persistentvolumeclaims;serviceaccounts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;update
 
 func (r *HarborClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -77,12 +96,18 @@ func (r *HarborClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 
 	var harborCluster goharborv1.HarborCluster
 	if err := r.Get(ctx, req.NamespacedName, &harborCluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			// the usage metrics are kept by the operator, they are not garbage collected with the HarborCluster
+			storage.DeleteUsageMetrics(req.Namespace, req.Name)
+			return ctrl.Result{}, nil
+		}
 		log.Error(err, "unable to fetch HarborCluster")
-		return ReconcileWaitResult, client.IgnoreNotFound(err)
+		return ReconcileWaitResult, err
 	}
 
 	// harborCluster will be gracefully deleted by server when DeletionTimestamp is non-null
 	if harborCluster.DeletionTimestamp != nil {
+		storage.DeleteUsageMetrics(harborCluster.Namespace, harborCluster.Name)
 		return ReconcileWaitResult, nil
 	}
 
@@ -112,10 +137,11 @@ func (r *HarborClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 		return ReconcileWaitResult, err
 	}
 
-	dbStatus, err := r.Database(ctx, &harborCluster, option).Reconcile()
-	componentToStatus[goharborv1.ComponentDatabase] = dbStatus
+	// the database is reconciled after storage, the WAL of inCluster database is archived to storage.
+	storageStatus, err := r.Storage(ctx, &harborCluster, option).Reconcile()
+	componentToStatus[goharborv1.ComponentStorage] = storageStatus
 	if err != nil {
-		log.Error(err, "error when reconcile database component.")
+		log.Error(err, "error when reconcile storage component.")
 		updateErr := r.UpdateHarborClusterStatus(ctx, &harborCluster, componentToStatus)
 		if updateErr != nil {
 			log.Error(updateErr, "update harbor cluster status")
@@ -123,10 +149,21 @@ func (r *HarborClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 		return ReconcileWaitResult, err
 	}
 
-	storageStatus, err := r.Storage(ctx, &harborCluster, option).Reconcile()
-	componentToStatus[goharborv1.ComponentStorage] = storageStatus
+	// the usage of storage does not block harbor, the warning is only reported in status.
+	if storageStatus != nil && storageStatus.Condition.Status == corev1.ConditionTrue {
+		usageStatus, err := r.StorageUsage(ctx, &harborCluster, option).Reconcile()
+		if err != nil {
+			log.Error(err, "error when collect storage usage.")
+		}
+		if usageStatus != nil {
+			componentToStatus[goharborv1.ComponentStorageUsage] = usageStatus
+		}
+	}
+
+	dbStatus, err := r.Database(ctx, &harborCluster, componentToStatus, option).Reconcile()
+	componentToStatus[goharborv1.ComponentDatabase] = dbStatus
 	if err != nil {
-		log.Error(err, "error when reconcile storage component.")
+		log.Error(err, "error when reconcile database component.")
 		updateErr := r.UpdateHarborClusterStatus(ctx, &harborCluster, componentToStatus)
 		if updateErr != nil {
 			log.Error(updateErr, "update harbor cluster status")
@@ -134,6 +171,25 @@ func (r *HarborClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 		return ReconcileWaitResult, err
 	}
 
+	// the database backup and credential rotation do not block harbor, the failures are only reported in status.
+	if dbStatus != nil && dbStatus.Condition.Status == corev1.ConditionTrue {
+		backupStatus, err := r.DatabaseBackup(ctx, &harborCluster, componentToStatus, option).Reconcile()
+		if err != nil {
+			log.Error(err, "error when reconcile database backup.")
+		}
+		if backupStatus != nil {
+			componentToStatus[goharborv1.ComponentDatabaseBackup] = backupStatus
+		}
+
+		credentialStatus, err := r.DatabaseCredentialRotation(ctx, &harborCluster, componentToStatus, option).Reconcile()
+		if err != nil {
+			log.Error(err, "error when reconcile database credential rotation.")
+		}
+		if credentialStatus != nil {
+			componentToStatus[goharborv1.ComponentDatabaseCredential] = credentialStatus
+		}
+	}
+
 	// if components is not all ready, requeue the HarborCluster
 	if !r.ComponentsAreAllReady(componentToStatus) {
 		log.Info("components not all ready.",
@@ -144,6 +200,26 @@ func (r *HarborClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 		return ReconcileWaitResult, err
 	}
 
+	// harbor is not reconciled until the requested database restore is completed.
+	restoreStatus, err := r.DatabaseRestore(ctx, &harborCluster, componentToStatus, option).Reconcile()
+	if restoreStatus != nil {
+		componentToStatus[goharborv1.ComponentDatabaseRestore] = restoreStatus
+	}
+	if err != nil {
+		log.Error(err, "error when reconcile database restore.")
+		updateErr := r.UpdateHarborClusterStatus(ctx, &harborCluster, componentToStatus)
+		if updateErr != nil {
+			log.Error(updateErr, "update harbor cluster status")
+		}
+		return ReconcileWaitResult, err
+	}
+	if restoreStatus != nil && restoreStatus.Condition.Status != corev1.ConditionTrue {
+		log.Info("database restore not completed.",
+			string(goharborv1.ComponentDatabaseRestore), restoreStatus)
+		err = r.UpdateHarborClusterStatus(ctx, &harborCluster, componentToStatus)
+		return ReconcileWaitResult, err
+	}
+
 	getRegistry := func() *string {
 		if harborCluster.Spec.ImageSource != nil && harborCluster.Spec.ImageSource.Registry != "" {
 			return &harborCluster.Spec.ImageSource.Registry
@@ -188,7 +264,7 @@ func (r *HarborClusterReconciler) ComponentsAreAllReady(serviceToMap map[goharbo
 			return false
 		}
 
-		if status.Condition.Type == goharborv1.ServiceReady {
+		if NonBlockingConditionTypes[status.Condition.Type] {
 			continue
 		}
 		if status.Condition.Status != corev1.ConditionTrue {
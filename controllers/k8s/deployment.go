@@ -0,0 +1,18 @@
+package k8s
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// IsDeploymentRolledOut returns whether all replicas of the deployment
+// run the latest observed generation and are available.
+func IsDeploymentRolledOut(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas == replicas &&
+		deployment.Status.AvailableReplicas == replicas &&
+		deployment.Status.Replicas == replicas
+}
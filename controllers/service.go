@@ -25,11 +25,23 @@ type ServiceGetter interface {
 	Cache(ctx context.Context, harborCluster *goharborv1.HarborCluster, options *GetOptions) Reconciler
 
 	// For database
-	Database(ctx context.Context, harborCluster *goharborv1.HarborCluster, options *GetOptions) Reconciler
+	Database(ctx context.Context, harborCluster *goharborv1.HarborCluster, componentToCRStatus map[goharborv1.Component]*lcm.CRStatus, options *GetOptions) Reconciler
+
+	// For database backup
+	DatabaseBackup(ctx context.Context, harborCluster *goharborv1.HarborCluster, componentToCRStatus map[goharborv1.Component]*lcm.CRStatus, options *GetOptions) Reconciler
+
+	// For database restore
+	DatabaseRestore(ctx context.Context, harborCluster *goharborv1.HarborCluster, componentToCRStatus map[goharborv1.Component]*lcm.CRStatus, options *GetOptions) Reconciler
+
+	// For database credential rotation
+	DatabaseCredentialRotation(ctx context.Context, harborCluster *goharborv1.HarborCluster, componentToCRStatus map[goharborv1.Component]*lcm.CRStatus, options *GetOptions) Reconciler
 
 	// For storage
 	Storage(ctx context.Context, harborCluster *goharborv1.HarborCluster, options *GetOptions) Reconciler
 
+	// For the usage of storage
+	StorageUsage(ctx context.Context, harborCluster *goharborv1.HarborCluster, options *GetOptions) Reconciler
+
 	// For harbor itself
 	Harbor(ctx context.Context, harborCluster *goharborv1.HarborCluster, componentToCRStatus map[goharborv1.Component]*lcm.CRStatus, options *GetOptions) Reconciler
 }
@@ -58,15 +70,63 @@ func (impl *ServiceGetterImpl) Cache(ctx context.Context, harborCluster *goharbo
 	}
 }
 
-func (impl *ServiceGetterImpl) Database(ctx context.Context, harborCluster *goharborv1.HarborCluster, options *GetOptions) Reconciler {
+func (impl *ServiceGetterImpl) Database(ctx context.Context, harborCluster *goharborv1.HarborCluster, componentToCRStatus map[goharborv1.Component]*lcm.CRStatus, options *GetOptions) Reconciler {
 	return &database.PostgreSQLReconciler{
-		HarborCluster: harborCluster,
-		Client:        options.Client,
-		Recorder:      options.Recorder,
-		Log:           options.Log,
-		DClient:       options.DClient,
-		Scheme:        options.Scheme,
-		Ctx:           ctx,
+		HarborCluster:       harborCluster,
+		Client:              options.Client,
+		Recorder:            options.Recorder,
+		Log:                 options.Log,
+		DClient:             options.DClient,
+		Scheme:              options.Scheme,
+		Ctx:                 ctx,
+		ComponentToCRStatus: componentToCRStatus,
+	}
+}
+
+func (impl *ServiceGetterImpl) DatabaseBackup(ctx context.Context, harborCluster *goharborv1.HarborCluster, componentToCRStatus map[goharborv1.Component]*lcm.CRStatus, options *GetOptions) Reconciler {
+	return &database.BackupReconciler{
+		PostgreSQLReconciler: database.PostgreSQLReconciler{
+			HarborCluster:       harborCluster,
+			Client:              options.Client,
+			Recorder:            options.Recorder,
+			Log:                 options.Log,
+			DClient:             options.DClient,
+			Scheme:              options.Scheme,
+			Ctx:                 ctx,
+			ComponentToCRStatus: componentToCRStatus,
+		},
+	}
+}
+
+func (impl *ServiceGetterImpl) DatabaseRestore(ctx context.Context, harborCluster *goharborv1.HarborCluster, componentToCRStatus map[goharborv1.Component]*lcm.CRStatus, options *GetOptions) Reconciler {
+	return &database.RestoreReconciler{
+		BackupReconciler: database.BackupReconciler{
+			PostgreSQLReconciler: database.PostgreSQLReconciler{
+				HarborCluster:       harborCluster,
+				Client:              options.Client,
+				Recorder:            options.Recorder,
+				Log:                 options.Log,
+				DClient:             options.DClient,
+				Scheme:              options.Scheme,
+				Ctx:                 ctx,
+				ComponentToCRStatus: componentToCRStatus,
+			},
+		},
+	}
+}
+
+func (impl *ServiceGetterImpl) DatabaseCredentialRotation(ctx context.Context, harborCluster *goharborv1.HarborCluster, componentToCRStatus map[goharborv1.Component]*lcm.CRStatus, options *GetOptions) Reconciler {
+	return &database.CredentialRotationReconciler{
+		PostgreSQLReconciler: database.PostgreSQLReconciler{
+			HarborCluster:       harborCluster,
+			Client:              options.Client,
+			Recorder:            options.Recorder,
+			Log:                 options.Log,
+			DClient:             options.DClient,
+			Scheme:              options.Scheme,
+			Ctx:                 ctx,
+			ComponentToCRStatus: componentToCRStatus,
+		},
 	}
 }
 
@@ -80,6 +140,18 @@ func (impl *ServiceGetterImpl) Storage(ctx context.Context, harborCluster *gohar
 	}
 }
 
+func (impl *ServiceGetterImpl) StorageUsage(ctx context.Context, harborCluster *goharborv1.HarborCluster, options *GetOptions) Reconciler {
+	return &storage.UsageReconciler{
+		MinIOReconciler: storage.MinIOReconciler{
+			HarborCluster: harborCluster,
+			KubeClient:    options.Client,
+			Ctx:           ctx,
+			Log:           options.Log,
+			Recorder:      options.Recorder,
+		},
+	}
+}
+
 func (impl *ServiceGetterImpl) Harbor(ctx context.Context, harborCluster *goharborv1.HarborCluster, componentToCRStatus map[goharborv1.Component]*lcm.CRStatus, options *GetOptions) Reconciler {
 	return &harbor.HarborReconciler{
 		HarborCluster:       harborCluster,
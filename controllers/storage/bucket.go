@@ -0,0 +1,108 @@
+package storage
+
+import (
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+)
+
+// ReconcileBuckets creates the buckets required by harbor components, and reconciles their properties
+// to the bucket policy in spec. The properties are neither read nor changed if there is no bucket policy,
+// since they are not supported by the old MinIO versions. The current properties are reported in status.
+func (m *MinIOReconciler) ReconcileBuckets() error {
+	policy := m.getBucketPolicy()
+
+	var statuses []goharborv1.StorageBucketStatus
+	for _, bucket := range m.getMinIOBuckets() {
+		exists, err := m.MinioClient.IsBucketExists(bucket)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			m.Log.Info("Creating bucket in minIO.",
+				"namespace", m.HarborCluster.Namespace, "name", m.HarborCluster.Name, "bucket", bucket)
+			if policy != nil && policy.ObjectLocking {
+				err = m.MinioClient.CreateBucketWithObjectLock(bucket)
+			} else {
+				err = m.MinioClient.CreateBucket(bucket)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		status, err := m.reconcileBucket(bucket, policy)
+		if err != nil {
+			return err
+		}
+		statuses = append(statuses, status)
+	}
+
+	m.HarborCluster.Status.StorageBuckets = statuses
+	return nil
+}
+
+// reconcileBucket reconciles the versioning and quota of the bucket.
+// Object locking can only be enabled on creation, versioning can not be suspended if it is enabled.
+func (m *MinIOReconciler) reconcileBucket(bucket string, policy *goharborv1.MinIOBucketPolicy) (goharborv1.StorageBucketStatus, error) {
+	status := goharborv1.StorageBucketStatus{Name: bucket}
+	if policy == nil {
+		return status, nil
+	}
+
+	locked, err := m.MinioClient.IsObjectLockEnabled(bucket)
+	if err != nil {
+		return status, err
+	}
+	status.ObjectLocking = locked
+
+	status.Versioning, err = m.MinioClient.GetBucketVersioning(bucket)
+	if err != nil {
+		return status, err
+	}
+
+	status.Quota, err = m.MinioClient.GetBucketQuota(bucket)
+	if err != nil {
+		return status, err
+	}
+
+	if policy.ObjectLocking && !locked {
+		m.Log.Info("Object locking can not be enabled on the existing bucket.",
+			"namespace", m.HarborCluster.Namespace, "name", m.HarborCluster.Name, "bucket", bucket)
+	}
+
+	versioning := policy.Versioning || locked
+	if versioning && status.Versioning != BucketVersioningEnabled {
+		m.Log.Info("Enable versioning of bucket.", "namespace", m.HarborCluster.Namespace, "bucket", bucket)
+		if err := m.MinioClient.SetBucketVersioning(bucket, true); err != nil {
+			return status, err
+		}
+		status.Versioning = BucketVersioningEnabled
+	} else if !versioning && status.Versioning == BucketVersioningEnabled {
+		m.Log.Info("Suspend versioning of bucket.", "namespace", m.HarborCluster.Namespace, "bucket", bucket)
+		if err := m.MinioClient.SetBucketVersioning(bucket, false); err != nil {
+			return status, err
+		}
+		status.Versioning = BucketVersioningSuspended
+	}
+
+	var quota int64
+	if policy.Quota != nil {
+		quota = policy.Quota.Value()
+	}
+	if status.Quota != quota {
+		m.Log.Info("Update quota of bucket.", "namespace", m.HarborCluster.Namespace, "bucket", bucket, "quota", quota)
+		if err := m.MinioClient.SetBucketQuota(bucket, quota); err != nil {
+			return status, err
+		}
+		status.Quota = quota
+	}
+
+	return status, nil
+}
+
+func (m *MinIOReconciler) getBucketPolicy() *goharborv1.MinIOBucketPolicy {
+	if spec := m.HarborCluster.Spec.Storage.InCluster.Spec; spec != nil {
+		return spec.BucketPolicy
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/controllers/harbor"
+	minio "github.com/goharbor/harbor-cluster-operator/controllers/storage/minio/api/v1"
+	"github.com/google/go-cmp/cmp"
+	certv1 "github.com/jetstack/cert-manager/pkg/apis/certmanager/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// MinIOCertSecretType makes minIO operator read the certificate from the keys written by cert-manager
+	MinIOCertSecretType = "cert-manager.io/v1alpha2"
+
+	MinIOCertSuffix = "tls"
+
+	// MinIOCABundleSuffix is the suffix of the secret of CA bundle trusted by registry and chart museum
+	MinIOCABundleSuffix = "ca-bundle"
+	// StorageCAVolumeName is the volume of CA bundle injected into registry and chart museum pods,
+	// the bundle is mounted in the system certificate directory which is read by the go runtime.
+	StorageCAVolumeName = "harbor-cluster-storage-ca"
+	StorageCAMountPath  = "/etc/ssl/certs/harbor-cluster-storage-ca.crt"
+	StorageCAKey        = "ca.crt"
+)
+
+// caBundleSecretName returns the name of the secret of CA bundle trusted by registry and chart museum
+func caBundleSecretName(harborClusterName string) string {
+	return harborClusterName + "-" + DefaultMinIO + "-" + MinIOCABundleSuffix
+}
+
+// isMinIOTLSEnabled returns whether minIO is served in HTTPS
+func (m *MinIOReconciler) isMinIOTLSEnabled() bool {
+	return m.HarborCluster.Spec.Storage.InCluster.Spec.EnableTLS
+}
+
+func (m *MinIOReconciler) getCertificateName() string {
+	return m.getServiceName() + "-" + MinIOCertSuffix
+}
+
+// getExternalCertSecret returns the certificate secret of minIO tenant, it is nil if TLS is disabled
+func (m *MinIOReconciler) getExternalCertSecret() *minio.LocalCertificateReference {
+	if !m.isMinIOTLSEnabled() {
+		return nil
+	}
+	return &minio.LocalCertificateReference{
+		Name: m.getCertificateName(),
+		Type: MinIOCertSecretType,
+	}
+}
+
+// getCertificateDNSNames returns the names of minIO service and the pods behind the headless service of tenant
+func (m *MinIOReconciler) getCertificateDNSNames() []string {
+	service := m.getServiceName()
+	headless := service + "-hl-svc"
+	namespace := m.HarborCluster.Namespace
+	return []string{
+		service,
+		fmt.Sprintf("%s.%s", service, namespace),
+		fmt.Sprintf("%s.%s.svc", service, namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", service, namespace),
+		fmt.Sprintf("*.%s.%s.svc", headless, namespace),
+		fmt.Sprintf("*.%s.%s.svc.cluster.local", headless, namespace),
+	}
+}
+
+func (m *MinIOReconciler) generateCertificate() *certv1.Certificate {
+	return &certv1.Certificate{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: certv1.SchemeGroupVersion.String(),
+			Kind:       certv1.CertificateKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        m.getCertificateName(),
+			Namespace:   m.HarborCluster.Namespace,
+			Labels:      m.getLabels(),
+			Annotations: m.generateAnnotations(),
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(m.HarborCluster, goharborv1.HarborClusterGVK),
+			},
+		},
+		Spec: certv1.CertificateSpec{
+			CommonName: m.getServiceName(),
+			DNSNames:   m.getCertificateDNSNames(),
+			SecretName: m.getCertificateName(),
+			IssuerRef:  m.HarborCluster.Spec.CertificateIssuerRef,
+			Usages:     []certv1.KeyUsage{certv1.UsageServerAuth, certv1.UsageDigitalSignature, certv1.UsageKeyEncipherment},
+		},
+	}
+}
+
+// ReconcileCertificate issues the serving certificate of minIO from CertificateIssuerRef.
+// The issuer is created here if it does not exist, since harbor is provisioned only after storage is ready.
+func (m *MinIOReconciler) ReconcileCertificate() error {
+	if !m.isMinIOTLSEnabled() {
+		return nil
+	}
+
+	err := harbor.EnsureIssuer(m.KubeClient, types.NamespacedName{
+		Namespace: m.HarborCluster.Namespace,
+		Name:      m.HarborCluster.Spec.CertificateIssuerRef.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	desired := m.generateCertificate()
+	var current certv1.Certificate
+	err = m.KubeClient.Get(types.NamespacedName{Namespace: desired.Namespace, Name: desired.Name}, &current)
+	if k8serror.IsNotFound(err) {
+		return m.KubeClient.Create(desired)
+	} else if err != nil {
+		return err
+	}
+
+	if cmp.Equal(desired.Spec, current.Spec) {
+		return nil
+	}
+
+	m.Log.Info("Update minIO certificate.",
+		"namespace", current.Namespace, "name", current.Name)
+
+	current.Spec = desired.Spec
+	return m.KubeClient.Update(&current)
+}
+
+// getMinIOCA returns the CA of minIO certificate, the certificate itself is the CA if it is self signed.
+func (m *MinIOReconciler) getMinIOCA() ([]byte, error) {
+	var secret corev1.Secret
+	err := m.KubeClient.Get(types.NamespacedName{Namespace: m.HarborCluster.Namespace, Name: m.getCertificateName()}, &secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if ca := secret.Data["ca.crt"]; len(ca) > 0 {
+		return ca, nil
+	}
+	if cert := secret.Data[corev1.TLSCertKey]; len(cert) > 0 {
+		return cert, nil
+	}
+	return nil, fmt.Errorf("no CA found in secret %s", secret.Name)
+}
+
+// ReconcileCABundle keeps the CA bundle trusted by registry and chart museum in sync with the minIO certificate.
+// With redirect, the bundle contains the CA of the public TLS secret as well, since the storage is accessed
+// through the ingress of minIO. It does nothing if minIO is served in HTTP.
+func (m *MinIOReconciler) ReconcileCABundle() error {
+	if !m.isMinIOTLSEnabled() {
+		return nil
+	}
+
+	bundle, err := m.getMinIOCA()
+	if err != nil {
+		return err
+	}
+
+	if !m.HarborCluster.Spec.DisableRedirect && m.HarborCluster.Spec.TLSSecret != "" {
+		var secret corev1.Secret
+		err := m.KubeClient.Get(types.NamespacedName{Namespace: m.HarborCluster.Namespace, Name: m.HarborCluster.Spec.TLSSecret}, &secret)
+		if err != nil {
+			return err
+		}
+		ca := secret.Data["ca.crt"]
+		if len(ca) == 0 {
+			ca = secret.Data[corev1.TLSCertKey]
+		}
+		bundle = append(append(bundle, '\n'), ca...)
+	}
+
+	return m.applySecret(&corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        caBundleSecretName(m.HarborCluster.Name),
+			Namespace:   m.HarborCluster.Namespace,
+			Labels:      m.getLabels(),
+			Annotations: m.generateAnnotations(),
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(m.HarborCluster, goharborv1.HarborClusterGVK),
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			StorageCAKey: bundle,
+		},
+	})
+}
+
+// newTLSTransport returns the transport which trusts the CA only
+func newTLSTransport(ca []byte) (*http.Transport, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("invalid CA of minIO")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	return transport, nil
+}
@@ -1,18 +1,53 @@
 package storage
 
 import (
-	minv6 "github.com/minio/minio-go/v6"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	minv6 "github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/signer"
+)
+
+const (
+	// MinIOAdminAPIPrefix is the path prefix of MinIO admin API
+	MinIOAdminAPIPrefix = "/minio/admin/v3"
+
+	BucketVersioningEnabled   = "Enabled"
+	BucketVersioningSuspended = "Suspended"
 )
 
 type Minio interface {
 	IsBucketExists(bucket string) (bool, error)
 	CreateBucket(bucket string) error
+	CreateBucketWithObjectLock(bucket string) error
+
+	GetBucketVersioning(bucket string) (string, error)
+	SetBucketVersioning(bucket string, enabled bool) error
+	GetBucketQuota(bucket string) (int64, error)
+	SetBucketQuota(bucket string, quota int64) error
+	IsObjectLockEnabled(bucket string) (bool, error)
+
+	GetDataUsage() (*DataUsage, error)
+	GetDrives() ([]Drive, error)
 }
 
 type MinioClient struct {
 	Client   *minv6.Client
 	Location string
+
+	endpoint  string
+	accessKey string
+	secretKey string
+	secure    bool
+	transport http.RoundTripper
 }
 
 func GetMinioClient(endpoint, accessKeyID, secretAccessKey, location string, useSSL bool) (*MinioClient, error) {
@@ -24,11 +59,21 @@ func GetMinioClient(endpoint, accessKeyID, secretAccessKey, location string, use
 	}
 
 	return &MinioClient{
-		Client:   client,
-		Location: location,
+		Client:    client,
+		Location:  location,
+		endpoint:  endpoint,
+		accessKey: accessKeyID,
+		secretKey: secretAccessKey,
+		secure:    useSSL,
 	}, nil
 }
 
+// SetTransport makes both the S3 and admin requests use the transport, e.g. the one trusting the CA of minIO
+func (m *MinioClient) SetTransport(transport http.RoundTripper) {
+	m.transport = transport
+	m.Client.SetCustomTransport(transport)
+}
+
 func (m MinioClient) IsBucketExists(bucket string) (bool, error) {
 	exists, err := m.Client.BucketExists(bucket)
 	if err != nil {
@@ -46,3 +91,197 @@ func (m MinioClient) CreateBucket(bucket string) error {
 	}
 	return nil
 }
+
+// CreateBucketWithObjectLock creates the bucket with object locking, which enables versioning as well
+func (m MinioClient) CreateBucketWithObjectLock(bucket string) error {
+	return m.Client.MakeBucketWithObjectLock(bucket, m.Location)
+}
+
+// GetBucketVersioning returns Enabled, Suspended or empty if versioning is never enabled
+func (m MinioClient) GetBucketVersioning(bucket string) (string, error) {
+	config, err := m.Client.GetBucketVersioning(bucket)
+	if err != nil {
+		return "", err
+	}
+	return config.Status, nil
+}
+
+// SetBucketVersioning enables or suspends versioning of the bucket
+func (m MinioClient) SetBucketVersioning(bucket string, enabled bool) error {
+	if enabled {
+		return m.Client.EnableVersioning(bucket)
+	}
+	return m.Client.DisableVersioning(bucket)
+}
+
+// IsObjectLockEnabled returns whether the bucket is created with object locking
+func (m MinioClient) IsObjectLockEnabled(bucket string) (bool, error) {
+	enabled, _, _, _, err := m.Client.GetObjectLockConfig(bucket)
+	if err != nil {
+		if minv6.ToErrorResponse(err).Code == "ObjectLockConfigurationNotFoundError" {
+			return false, nil
+		}
+		return false, err
+	}
+	return enabled == "Enabled", nil
+}
+
+type bucketQuota struct {
+	Quota     int64  `json:"quota"`
+	QuotaType string `json:"quotatype,omitempty"`
+}
+
+// GetBucketQuota returns the hard quota of the bucket in bytes, 0 means there is no quota.
+func (m MinioClient) GetBucketQuota(bucket string) (int64, error) {
+	resp, err := m.doAdmin(http.MethodGet, "get-bucket-quota", bucket, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("get quota of bucket %s: %s %s", bucket, resp.Status, string(body))
+	}
+
+	var quota bucketQuota
+	if err := json.Unmarshal(body, &quota); err != nil {
+		return 0, err
+	}
+	return quota.Quota, nil
+}
+
+// SetBucketQuota sets the hard quota of the bucket in bytes, the quota is cleared if it is 0.
+func (m MinioClient) SetBucketQuota(bucket string, quota int64) error {
+	data, err := json.Marshal(bucketQuota{Quota: quota, QuotaType: "hard"})
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.doAdmin(http.MethodPut, "set-bucket-quota", bucket, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("set quota of bucket %s: %s %s", bucket, resp.Status, string(body))
+	}
+	return nil
+}
+
+// DataUsage is the usage of buckets scanned by minIO in background, it is refreshed every scan cycle.
+type DataUsage struct {
+	LastUpdate       time.Time              `json:"lastUpdate"`
+	ObjectsCount     uint64                 `json:"objectsCount"`
+	ObjectsTotalSize uint64                 `json:"objectsTotalSize"`
+	BucketsUsage     map[string]BucketUsage `json:"bucketsUsageInfo"`
+	// BucketsSizes is reported by the minIO releases without BucketsUsage
+	BucketsSizes map[string]uint64 `json:"bucketsSizes"`
+}
+
+type BucketUsage struct {
+	Size         uint64 `json:"size"`
+	ObjectsCount uint64 `json:"objectsCount"`
+}
+
+// GetBucketUsage returns the usage of the bucket, the object count is 0 if minIO only reports the size.
+func (d *DataUsage) GetBucketUsage(bucket string) BucketUsage {
+	if usage, ok := d.BucketsUsage[bucket]; ok {
+		return usage
+	}
+	return BucketUsage{Size: d.BucketsSizes[bucket]}
+}
+
+// Drive is a drive of minIO server
+type Drive struct {
+	Endpoint   string `json:"endpoint"`
+	State      string `json:"state"`
+	TotalSpace uint64 `json:"totalspace"`
+	UsedSpace  uint64 `json:"usedspace"`
+}
+
+type serverInfo struct {
+	Servers []struct {
+		Drives []Drive `json:"drives"`
+	} `json:"servers"`
+}
+
+// GetDataUsage returns the usage of buckets
+func (m MinioClient) GetDataUsage() (*DataUsage, error) {
+	var usage DataUsage
+	if err := m.getAdmin("datausageinfo", &usage); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// GetDrives returns the drives of all minIO servers, the state of healthy drive is "ok".
+func (m MinioClient) GetDrives() ([]Drive, error) {
+	var info serverInfo
+	if err := m.getAdmin("info", &info); err != nil {
+		return nil, err
+	}
+
+	var drives []Drive
+	for _, server := range info.Servers {
+		drives = append(drives, server.Drives...)
+	}
+	return drives, nil
+}
+
+// getAdmin gets the result of admin API in JSON
+func (m MinioClient) getAdmin(api string, result interface{}) error {
+	resp, err := m.doAdmin(http.MethodGet, api, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get %s of minIO: %s %s", api, resp.Status, string(body))
+	}
+	return json.Unmarshal(body, result)
+}
+
+// doAdmin sends the request signed with signature v4 to MinIO admin API
+func (m MinioClient) doAdmin(method, api, bucket string, body []byte) (*http.Response, error) {
+	scheme := "http"
+	if m.secure {
+		scheme = "https"
+	}
+	u := url.URL{
+		Scheme: scheme,
+		Host:   m.endpoint,
+		Path:   MinIOAdminAPIPrefix + "/" + api,
+	}
+	if bucket != "" {
+		u.RawQuery = url.Values{"bucket": {bucket}}.Encode()
+	}
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	req.ContentLength = int64(len(body))
+
+	req = signer.SignV4(*req, m.accessKey, m.secretKey, "", m.Location)
+	client := http.DefaultClient
+	if m.transport != nil {
+		client = &http.Client{Transport: m.transport}
+	}
+	return client.Do(req)
+}
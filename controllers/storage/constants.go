@@ -9,14 +9,59 @@ const (
 	CreateMinIOIngressError = "Create ingress of minIO error"
 	GetMinIOSecretError     = "Get minIO secret error"
 	CreateMinIOError        = "Create minIO CR error"
-	ScaleMinIOError         = "Scale minIO error"
+	ExpandMinIOError        = "Expand minIO error"
+
+	CreateMinIOCertificateError = "Create certificate of minIO error"
 
 	CreateExternalSecretError = "Create external storage secret error"
 	GetExternalSecretError    = "Get external storage secret error"
 	UpdateExternalSecretError = "Update external storage secret error"
 	NotSupportType            = "The type of storage are not supported"
 	CreateDefaultBucketError  = "Create default bucket in minIO Error"
+	ReconcileBucketError      = "Reconcile buckets in minIO error"
+	CreateMinIOCABundleError  = "Create minIO CA bundle error"
 
 	CreateChartMuseumStorageSecretError   = "Create chart museum storage secret err"
 	GenerateChartMuseumStorageSecretError = "Generate chart museum storage secret err"
+	UpdateChartMuseumStorageSecretError   = "Update chart museum storage secret err"
+
+	// the capabilities of external storage checked by the probe
+	StorageConnectError        = "Connect storage error"
+	StorageBucketNotFoundError = "Storage bucket not found"
+	StoragePutObjectError      = "Put object to storage error"
+	StorageGetObjectError      = "Get object from storage error"
+	StorageDeleteObjectError   = "Delete object from storage error"
+
+	MigrateStorageError    = "Migrate storage error"
+	MigratingStorageReason = "Storage migrating"
+
+	FileSystemClaimError       = "Filesystem storage claim error"
+	FileSystemAccessModeError  = "Filesystem storage access mode error"
+	ExpandFileSystemClaimError = "Expand filesystem storage claim error"
+	FileSystemNotMountedError  = "Filesystem storage not mounted error"
+
+	ReconcileServiceAccountError = "Reconcile storage service account error"
+	StorageUnverifiedReason      = "Storage unverified"
+
+	CollectStorageUsageError   = "Collect storage usage error"
+	StorageDrivesOfflineReason = "Storage drives offline"
+	StorageUsageHighReason     = "Storage usage high"
+)
+
+const (
+	ExpandingMinIO   = "MinIOExpanding"
+	MigratingStorage = "StorageMigrating"
+	MigratedStorage  = "StorageMigrated"
+
+	ExpandingFileSystemStorage = "FileSystemStorageExpanding"
+
+	MessageStorageMigrating = "Storage is migrating from %s to %s, harbor is read-only until the migration is completed."
+	MessageStorageMigrated  = "Storage is migrated from %s to %s, %d objects are copied."
+
+	MessageFileSystemStorageExpanding = "Filesystem storage is expanding from %s to %s."
+
+	MessageStorageUnverified = "The %s storage is not probed, since the operator can not act as its workload identity."
+
+	MessageStorageDrivesOffline = "%d of %d drives of minIO are offline."
+	MessageStorageUsageHigh     = "Storage usage %d%% is above the warning percentage %d%%."
 )
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"fmt"
+
+	minio "github.com/goharbor/harbor-cluster-operator/controllers/storage/minio/api/v1"
+	"github.com/goharbor/harbor-cluster-operator/lcm"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// getZones returns the zones of minIO tenant. The first zone is defined by the top level of spec,
+// and the zones in spec are appended to it.
+func (m *MinIOReconciler) getZones() []minio.Zone {
+	spec := m.HarborCluster.Spec.Storage.InCluster.Spec
+	zones := []minio.Zone{
+		{
+			Name:                DefaultZone,
+			Servers:             spec.Replicas,
+			VolumesPerServer:    spec.VolumesPerServer,
+			VolumeClaimTemplate: m.getVolumeClaimTemplate(),
+			Resources:           *m.getResourceRequirements(),
+		},
+	}
+
+	for _, z := range spec.Zones {
+		zone := minio.Zone{
+			Name:                z.Name,
+			Servers:             z.Servers,
+			VolumesPerServer:    z.VolumesPerServer,
+			VolumeClaimTemplate: z.VolumeClaimTemplate,
+			Resources:           *m.getResourceRequirements(),
+		}
+		if zone.VolumeClaimTemplate == nil {
+			zone.VolumeClaimTemplate = m.getVolumeClaimTemplate()
+		}
+		if z.Resources != nil {
+			zone.Resources = *z.Resources
+		}
+		zones = append(zones, zone)
+	}
+
+	return zones
+}
+
+// checkMinIOExpansion returns true if there are zones to append. MinIO can not resize the erasure sets
+// of the existing zones, so that they can not be modified or removed.
+func (m *MinIOReconciler) checkMinIOExpansion() (bool, error) {
+	current := m.CurrentMinIOCR.Spec.Zones
+	desired := m.DesiredMinIOCR.Spec.Zones
+
+	for i, zone := range current {
+		if i >= len(desired) {
+			return false, fmt.Errorf("zone %s of minIO can not be removed", zone.Name)
+		}
+		if zone.Name != desired[i].Name || zone.Servers != desired[i].Servers || zone.VolumesPerServer != desired[i].VolumesPerServer {
+			return false, fmt.Errorf("zone %s of minIO can not be modified", zone.Name)
+		}
+	}
+
+	return len(desired) > len(current), nil
+}
+
+// Expand appends the new zones to minIO tenant, the storage is ready again after the servers of all zones are available.
+func (m *MinIOReconciler) Expand() (*lcm.CRStatus, error) {
+	minioCR := m.CurrentMinIOCR
+	zones := m.DesiredMinIOCR.Spec.Zones[len(minioCR.Spec.Zones):]
+	for _, zone := range zones {
+		m.Recorder.Eventf(m.HarborCluster, corev1.EventTypeNormal, ExpandingMinIO,
+			"Append zone %s with %d servers and %d volumes per server to minIO", zone.Name, zone.Servers, zone.VolumesPerServer)
+	}
+
+	minioCR.Spec.Zones = append(minioCR.Spec.Zones, zones...)
+	err := m.KubeClient.Update(minioCR)
+	if err != nil {
+		return minioNotReadyStatus(ExpandMinIOError, err.Error()), err
+	}
+
+	return minioUnknownStatus(), nil
+}
+
+// getTotalServers returns the number of servers in all zones
+func getTotalServers(zones []minio.Zone) int32 {
+	var total int32
+	for _, zone := range zones {
+		total += zone.Servers
+	}
+	return total
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"testing"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestGetZones(t *testing.T) {
+	template := corev1.PersistentVolumeClaim{
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Gi")},
+			},
+		},
+	}
+	zoneTemplate := corev1.PersistentVolumeClaim{
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Ti")},
+			},
+		},
+	}
+	resources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+	}
+	zoneResources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+	}
+
+	m := &MinIOReconciler{HarborCluster: &goharborv1.HarborCluster{Spec: goharborv1.HarborClusterSpec{
+		Storage: &goharborv1.Storage{
+			Kind: inClusterStorage,
+			InCluster: &goharborv1.InCluster{Spec: &goharborv1.MinIOSpec{
+				Replicas:            4,
+				VolumesPerServer:    1,
+				VolumeClaimTemplate: template,
+				Resources:           resources,
+				Zones: []goharborv1.MinIOZone{
+					{Name: "zone-1", Servers: 2, VolumesPerServer: 2},
+					{Name: "zone-2", Servers: 4, VolumesPerServer: 4, VolumeClaimTemplate: &zoneTemplate, Resources: &zoneResources},
+				},
+			}},
+		},
+	}}}
+
+	cases := []struct {
+		name             string
+		servers          int32
+		volumesPerServer int32
+		storage          string
+		cpu              string
+	}{
+		{name: DefaultZone, servers: 4, volumesPerServer: 1, storage: "100Gi", cpu: "1"},
+		{name: "zone-1", servers: 2, volumesPerServer: 2, storage: "100Gi", cpu: "1"},
+		{name: "zone-2", servers: 4, volumesPerServer: 4, storage: "1Ti", cpu: "2"},
+	}
+
+	zones := m.getZones()
+	if len(zones) != len(cases) {
+		t.Fatalf("getZones() returns %d zones, want %d", len(zones), len(cases))
+	}
+	for i, c := range cases {
+		zone := zones[i]
+		if zone.Name != c.name || zone.Servers != c.servers || zone.VolumesPerServer != c.volumesPerServer {
+			t.Errorf("zone %d = %s %dx%d, want %s %dx%d", i, zone.Name, zone.Servers, zone.VolumesPerServer,
+				c.name, c.servers, c.volumesPerServer)
+		}
+		size := zone.VolumeClaimTemplate.Spec.Resources.Requests[corev1.ResourceStorage]
+		if size.Cmp(resource.MustParse(c.storage)) != 0 {
+			t.Errorf("zone %s storage = %s, want %s", zone.Name, size.String(), c.storage)
+		}
+		cpu := zone.Resources.Requests[corev1.ResourceCPU]
+		if cpu.Cmp(resource.MustParse(c.cpu)) != 0 {
+			t.Errorf("zone %s cpu = %s, want %s", zone.Name, cpu.String(), c.cpu)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/lcm"
+	harborv1 "github.com/goharbor/harbor-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	fileSystemStorage = "filesystem"
+
+	// FileSystemMountPath is the path where the claim is mounted in registry and chart museum pods,
+	// each of them mounts its own sub path of the claim.
+	FileSystemMountPath   = "/storage"
+	FileSystemClaimSuffix = "harbor-storage"
+	FileSystemVolumeName  = "harbor-cluster-storage"
+)
+
+// fileSystemClaimName returns the name of persistent volume claim of filesystem storage
+func fileSystemClaimName(harborClusterName string) string {
+	return harborClusterName + "-" + FileSystemClaimSuffix
+}
+
+// getFileSystemAccessMode returns the access mode of filesystem storage, default is ReadWriteMany
+func getFileSystemAccessMode(fs *goharborv1.FileSystem) corev1.PersistentVolumeAccessMode {
+	if fs.AccessMode == "" {
+		return corev1.ReadWriteMany
+	}
+	return fs.AccessMode
+}
+
+// ReconcileFileSystemClaim creates the persistent volume claim of filesystem storage and expands it to the size in spec.
+// It returns nil status if the claim is reconciled. The claim is not waited to be bound, since the storage class
+// may bind it only after the registry pods are scheduled.
+func (m *MinIOReconciler) ReconcileFileSystemClaim() (*lcm.CRStatus, error) {
+	fs := m.HarborCluster.Spec.Storage.FileSystem
+	if fs == nil {
+		return minioNotReadyStatus(FileSystemClaimError, "filesystem options are required"), nil
+	}
+
+	mode := getFileSystemAccessMode(fs)
+	if mode == corev1.ReadWriteOnce && m.HarborCluster.Spec.Replicas > 1 {
+		return minioNotReadyStatus(FileSystemAccessModeError,
+			fmt.Sprintf("%s filesystem storage requires a single replica, but replicas is %d", mode, m.HarborCluster.Spec.Replicas)), nil
+	}
+
+	var claim corev1.PersistentVolumeClaim
+	err := m.KubeClient.Get(types.NamespacedName{Namespace: m.HarborCluster.Namespace, Name: fileSystemClaimName(m.HarborCluster.Name)}, &claim)
+	if k8serror.IsNotFound(err) {
+		if err := m.KubeClient.Create(m.generateFileSystemClaim()); err != nil {
+			return minioNotReadyStatus(FileSystemClaimError, err.Error()), err
+		}
+		return nil, nil
+	} else if err != nil {
+		return minioNotReadyStatus(FileSystemClaimError, err.Error()), err
+	}
+
+	if len(claim.Spec.AccessModes) != 1 || claim.Spec.AccessModes[0] != mode {
+		return minioNotReadyStatus(FileSystemAccessModeError,
+			fmt.Sprintf("access mode of claim %s can not be changed to %s", claim.Name, mode)), nil
+	}
+
+	current := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	switch fs.Size.Cmp(current) {
+	case 0:
+		return nil, nil
+	case -1:
+		return minioNotReadyStatus(ExpandFileSystemClaimError,
+			fmt.Sprintf("claim %s can not be shrunk from %s to %s", claim.Name, current.String(), fs.Size.String())), nil
+	}
+
+	expandable, err := m.isClaimExpandable(&claim)
+	if err != nil {
+		return minioNotReadyStatus(ExpandFileSystemClaimError, err.Error()), err
+	}
+	if !expandable {
+		return minioNotReadyStatus(ExpandFileSystemClaimError,
+			fmt.Sprintf("storage class of claim %s does not allow volume expansion", claim.Name)), nil
+	}
+
+	m.Log.Info("Expand filesystem storage.",
+		"namespace", claim.Namespace, "name", claim.Name, "from", current.String(), "to", fs.Size.String())
+
+	claim.Spec.Resources.Requests[corev1.ResourceStorage] = fs.Size
+	if err := m.KubeClient.Update(&claim); err != nil {
+		return minioNotReadyStatus(ExpandFileSystemClaimError, err.Error()), err
+	}
+	m.Recorder.Event(m.HarborCluster, corev1.EventTypeNormal, ExpandingFileSystemStorage,
+		fmt.Sprintf(MessageFileSystemStorageExpanding, current.String(), fs.Size.String()))
+	return nil, nil
+}
+
+// generateFileSystemClaim returns the persistent volume claim of filesystem storage.
+// It is not owned by HarborCluster, so that the objects are kept after HarborCluster is deleted.
+func (m *MinIOReconciler) generateFileSystemClaim() *corev1.PersistentVolumeClaim {
+	fs := m.HarborCluster.Spec.Storage.FileSystem
+	claim := &corev1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "PersistentVolumeClaim",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fileSystemClaimName(m.HarborCluster.Name),
+			Namespace:   m.HarborCluster.Namespace,
+			Labels:      m.getLabels(),
+			Annotations: m.generateAnnotations(),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{getFileSystemAccessMode(fs)},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: fs.Size,
+				},
+			},
+		},
+	}
+	claim.Labels[LabelOfStorageType] = fileSystemStorage
+	if fs.StorageClassName != "" {
+		claim.Spec.StorageClassName = &fs.StorageClassName
+	}
+	return claim
+}
+
+// isClaimExpandable returns whether the storage class of the persistent volume claim allows volume expansion
+func (m *MinIOReconciler) isClaimExpandable(claim *corev1.PersistentVolumeClaim) (bool, error) {
+	if claim.Spec.StorageClassName == nil || *claim.Spec.StorageClassName == "" {
+		return false, nil
+	}
+
+	sc := &storagev1.StorageClass{}
+	if err := m.KubeClient.Get(types.NamespacedName{Name: *claim.Spec.StorageClassName}, sc); err != nil {
+		return false, err
+	}
+	return sc.AllowVolumeExpansion != nil && *sc.AllowVolumeExpansion, nil
+}
+
+// getPodsWithoutFileSystem returns the registry and chart museum pods which do not mount the claim,
+// e.g. the pods are created while the pod webhook is not applied. There is no pod before harbor is provisioned.
+func (m *MinIOReconciler) getPodsWithoutFileSystem() ([]string, error) {
+	var pods corev1.PodList
+	err := m.KubeClient.List(&client.ListOptions{
+		Namespace: m.HarborCluster.Namespace,
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			"harbor": fmt.Sprintf("%s-harbor", m.HarborCluster.Name),
+		}),
+	}, &pods)
+	if err != nil {
+		return nil, err
+	}
+
+	var unmounted []string
+	for _, pod := range pods.Items {
+		component := pod.Labels["app"]
+		if pod.DeletionTimestamp != nil || (component != harborv1.RegistryName && component != harborv1.ChartMuseumName) {
+			continue
+		}
+		if !isFileSystemMounted(&pod) {
+			unmounted = append(unmounted, pod.Name)
+		}
+	}
+	return unmounted, nil
+}
+
+// isFileSystemMounted returns whether the claim is mounted by the pod
+func isFileSystemMounted(pod *corev1.Pod) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == FileSystemVolumeName && volume.PersistentVolumeClaim != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// generateFileSystemSecret returns the storage secret of registry, the registry stores the objects in the mount path.
+func (m *MinIOReconciler) generateFileSystemSecret(labels map[string]string) (*corev1.Secret, error) {
+	dataJson, err := json.Marshal(map[string]string{
+		"rootdirectory": FileSystemMountPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        m.getExternalSecretName(),
+			Namespace:   m.HarborCluster.Namespace,
+			Labels:      labels,
+			Annotations: m.generateAnnotations(),
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(m.HarborCluster, goharborv1.HarborClusterGVK),
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			fileSystemStorage: dataJson,
+		},
+	}, nil
+}
+
+func (m *MinIOReconciler) generateFileSystemSecretForChartMuseum(labels map[string]string) *corev1.Secret {
+	return m.newChartMuseumSecret(labels, "local", map[string]string{
+		"LOCAL_ROOTDIR": FileSystemMountPath,
+	})
+}
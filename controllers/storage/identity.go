@@ -0,0 +1,92 @@
+package storage
+
+import (
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	ServiceAccountSuffix = "harbor-storage"
+
+	// EKSRoleArnAnnotation makes the pods of the service account assume the IAM role through IRSA
+	EKSRoleArnAnnotation = "eks.amazonaws.com/role-arn"
+	// GKEServiceAccountAnnotation makes the pods of the service account impersonate the google service account
+	GKEServiceAccountAnnotation = "iam.gke.io/gcp-service-account"
+)
+
+// serviceAccountName returns the name of service account used by registry and chart museum with workload identity
+func serviceAccountName(harborClusterName string) string {
+	return harborClusterName + "-" + ServiceAccountSuffix
+}
+
+// getWorkloadIdentityAnnotations returns the annotations of service account binding the cloud identity,
+// it is empty if the storage uses static keys.
+func getWorkloadIdentityAnnotations(storage *goharborv1.Storage) map[string]string {
+	switch storage.Kind {
+	case s3Storage:
+		if storage.S3 != nil && storage.S3.RoleArn != "" {
+			return map[string]string{EKSRoleArnAnnotation: storage.S3.RoleArn}
+		}
+	case gcsStorage:
+		if storage.Gcs != nil && storage.Gcs.ServiceAccount != "" {
+			return map[string]string{GKEServiceAccountAnnotation: storage.Gcs.ServiceAccount}
+		}
+	}
+	return nil
+}
+
+// ReconcileServiceAccount creates the service account of registry and chart museum annotated with the cloud identity,
+// it is deleted once the storage does not use workload identity.
+func (m *MinIOReconciler) ReconcileServiceAccount() error {
+	identity := getWorkloadIdentityAnnotations(m.HarborCluster.Spec.Storage)
+
+	var current corev1.ServiceAccount
+	err := m.KubeClient.Get(types.NamespacedName{Namespace: m.HarborCluster.Namespace, Name: serviceAccountName(m.HarborCluster.Name)}, &current)
+	if k8serror.IsNotFound(err) {
+		if len(identity) == 0 {
+			return nil
+		}
+		return m.KubeClient.Create(m.generateServiceAccount(identity))
+	} else if err != nil {
+		return err
+	}
+
+	if len(identity) == 0 {
+		m.Log.Info("Delete service account of workload identity.",
+			"namespace", current.Namespace, "name", current.Name)
+		return m.KubeClient.Delete(&current)
+	}
+
+	desired := m.generateServiceAccount(identity)
+	if cmp.Equal(desired.Annotations, current.Annotations) {
+		return nil
+	}
+
+	m.Log.Info("Update service account of workload identity.",
+		"namespace", current.Namespace, "name", current.Name)
+
+	current.Annotations = desired.Annotations
+	return m.KubeClient.Update(&current)
+}
+
+func (m *MinIOReconciler) generateServiceAccount(identity map[string]string) *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ServiceAccount",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        serviceAccountName(m.HarborCluster.Name),
+			Namespace:   m.HarborCluster.Namespace,
+			Labels:      m.getLabels(),
+			Annotations: identity,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(m.HarborCluster, goharborv1.HarborClusterGVK),
+			},
+		},
+	}
+}
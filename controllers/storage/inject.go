@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/controllers/k8s"
+	harborv1 "github.com/goharbor/harbor-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// FileSystemStorageLabel is set on the pods sharing a ReadWriteOnce claim, so that they are scheduled to the same node
+	FileSystemStorageLabel = "goharbor.io/filesystem-storage"
+
+	// FileSystemGroup is the group of harbor user, the files in the claim are owned by it
+	FileSystemGroup int64 = 10000
+
+	// GcsKeySecretSuffix is the suffix of the secret of the decoded gcs key, which is mounted into chart museum pods
+	// since harbor operator only exposes the storage secret of chart museum as environments.
+	GcsKeySecretSuffix = "harbor-storage-gcs-key"
+	GcsKeyVolumeName   = "harbor-cluster-gcs-key"
+	GcsKeyMountPath    = "/etc/harbor-cluster/gcs"
+	GcsKeyFile         = "key.json"
+)
+
+// gcsKeySecretName returns the name of the secret of the decoded gcs key
+func gcsKeySecretName(harborClusterName string) string {
+	return harborClusterName + "-" + GcsKeySecretSuffix
+}
+
+// The webhook is scoped to the pods of harbor by the object selector in config/default/webhook_pod_selector_patch.yaml,
+// it fails the pod creation if the storage can not be injected, otherwise registry would start without the storage.
+// The service account of workload identity is set in admission, since harbor operator can not set it in deployments.
+// The pod identity webhook of EKS reads the service account of pod, it must be invoked after this webhook,
+// so this webhook is configured with reinvocationPolicy IfNeeded, and the pod identity webhook must be configured
+// with reinvocationPolicy IfNeeded as well. GKE workload identity reads the service account at runtime.
+// +kubebuilder:webhook:path=/mutate-v1-pod,mutating=true,failurePolicy=fail,groups="",resources=pods,verbs=create,versions=v1,name=mpod.goharbor.io
+
+// StorageInjector mounts the claim of filesystem storage or the CA bundle of minIO into registry and chart museum pods,
+// mounts the gcs key into chart museum pods,
+// and sets the service account of workload identity to them, since harbor operator can not customize the pods of them.
+type StorageInjector struct {
+	Client  client.Client
+	decoder *admission.Decoder
+}
+
+// Handle injects the storage of HarborCluster into the pod of registry or chart museum
+func (i *StorageInjector) Handle(ctx context.Context, req admission.Request) admission.Response {
+	pod := &corev1.Pod{}
+	if err := i.decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	component := pod.Labels["app"]
+	if component != harborv1.RegistryName && component != harborv1.ChartMuseumName {
+		return admission.Allowed("not a storage component")
+	}
+
+	harborCluster, err := i.getHarborCluster(ctx, req.Namespace, pod.Labels["harbor"])
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if harborCluster == nil || harborCluster.Spec.Storage == nil {
+		return admission.Allowed("not managed by HarborCluster")
+	}
+
+	storage := harborCluster.Spec.Storage
+	switch {
+	case storage.Kind == fileSystemStorage && storage.FileSystem != nil:
+		injectFileSystemStorage(pod, harborCluster, component)
+	case storage.Kind == inClusterStorage && storage.InCluster != nil && storage.InCluster.Spec != nil && storage.InCluster.Spec.EnableTLS:
+		injectStorageCA(pod, harborCluster)
+	case storage.Kind == gcsStorage && storage.Gcs != nil && storage.Gcs.EncodedKey != "":
+		if component != harborv1.ChartMuseumName {
+			return admission.Allowed("registry reads the encoded key from its storage secret")
+		}
+		injectGcsKey(pod, harborCluster)
+	case len(getWorkloadIdentityAnnotations(storage)) > 0:
+		pod.Spec.ServiceAccountName = serviceAccountName(harborCluster.Name)
+	default:
+		return admission.Allowed("nothing to inject")
+	}
+
+	marshaledPod, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledPod)
+}
+
+// InjectDecoder injects the decoder of admission requests
+func (i *StorageInjector) InjectDecoder(d *admission.Decoder) error {
+	i.decoder = d
+	return nil
+}
+
+// getHarborCluster returns the HarborCluster owning the Harbor CR, it is nil if the Harbor CR is not managed by a HarborCluster.
+func (i *StorageInjector) getHarborCluster(ctx context.Context, namespace, harborName string) (*goharborv1.HarborCluster, error) {
+	if harborName == "" {
+		return nil, nil
+	}
+
+	var harborCR harborv1.Harbor
+	err := i.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: harborName}, &harborCR)
+	if k8serror.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	name := harborCR.Labels[k8s.HarborClusterNameLabel]
+	if name == "" {
+		return nil, nil
+	}
+
+	var harborCluster goharborv1.HarborCluster
+	err = i.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &harborCluster)
+	if k8serror.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &harborCluster, nil
+}
+
+// injectStorageCA mounts the CA bundle of minIO to all containers of the pod, so that the certificate of minIO
+// is verified by registry and chart museum.
+func injectStorageCA(pod *corev1.Pod, harborCluster *goharborv1.HarborCluster) {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == StorageCAVolumeName {
+			return
+		}
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: StorageCAVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: caBundleSecretName(harborCluster.Name),
+			},
+		},
+	})
+
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].VolumeMounts = append(pod.Spec.Containers[i].VolumeMounts, corev1.VolumeMount{
+			Name:      StorageCAVolumeName,
+			MountPath: StorageCAMountPath,
+			SubPath:   StorageCAKey,
+			ReadOnly:  true,
+		})
+	}
+}
+
+// injectGcsKey mounts the decoded gcs key to all containers of the chart museum pod,
+// GOOGLE_APPLICATION_CREDENTIALS in the storage secret of chart museum points to it.
+func injectGcsKey(pod *corev1.Pod, harborCluster *goharborv1.HarborCluster) {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == GcsKeyVolumeName {
+			return
+		}
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: GcsKeyVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: gcsKeySecretName(harborCluster.Name),
+			},
+		},
+	})
+
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].VolumeMounts = append(pod.Spec.Containers[i].VolumeMounts, corev1.VolumeMount{
+			Name:      GcsKeyVolumeName,
+			MountPath: GcsKeyMountPath,
+			ReadOnly:  true,
+		})
+	}
+}
+
+// injectFileSystemStorage mounts the sub path of the component in the claim to all containers of the pod.
+// The pods are scheduled to the same node if the claim is ReadWriteOnce.
+func injectFileSystemStorage(pod *corev1.Pod, harborCluster *goharborv1.HarborCluster, component string) {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == FileSystemVolumeName {
+			return
+		}
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: FileSystemVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: fileSystemClaimName(harborCluster.Name),
+			},
+		},
+	})
+
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].VolumeMounts = append(pod.Spec.Containers[i].VolumeMounts, corev1.VolumeMount{
+			Name:      FileSystemVolumeName,
+			MountPath: FileSystemMountPath,
+			SubPath:   component,
+		})
+	}
+
+	if pod.Spec.SecurityContext == nil {
+		pod.Spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	if pod.Spec.SecurityContext.FSGroup == nil {
+		fsGroup := FileSystemGroup
+		pod.Spec.SecurityContext.FSGroup = &fsGroup
+	}
+
+	if getFileSystemAccessMode(harborCluster.Spec.Storage.FileSystem) != corev1.ReadWriteOnce {
+		return
+	}
+
+	if pod.Labels == nil {
+		pod.Labels = map[string]string{}
+	}
+	pod.Labels[FileSystemStorageLabel] = harborCluster.Name
+
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &corev1.Affinity{}
+	}
+	if pod.Spec.Affinity.PodAffinity == nil {
+		pod.Spec.Affinity.PodAffinity = &corev1.PodAffinity{}
+	}
+	pod.Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+		pod.Spec.Affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution, corev1.PodAffinityTerm{
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					FileSystemStorageLabel: harborCluster.Name,
+				},
+			},
+			TopologyKey: corev1.LabelHostname,
+		})
+}
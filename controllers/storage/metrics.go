@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"sync"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	storageUsedBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "harbor_cluster_storage_used_bytes",
+		Help: "The bytes used on the drives of inCluster storage.",
+	}, []string{"namespace", "name"})
+
+	storageCapacityBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "harbor_cluster_storage_capacity_bytes",
+		Help: "The provisioned capacity of the volumes of inCluster storage.",
+	}, []string{"namespace", "name"})
+
+	storageDrives = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "harbor_cluster_storage_drives",
+		Help: "The number of drives of inCluster storage by state, online or offline.",
+	}, []string{"namespace", "name", "state"})
+
+	storageBucketSizeBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "harbor_cluster_storage_bucket_size_bytes",
+		Help: "The total size of objects in the bucket of inCluster storage.",
+	}, []string{"namespace", "name", "bucket"})
+
+	storageBucketObjects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "harbor_cluster_storage_bucket_objects",
+		Help: "The number of objects in the bucket of inCluster storage.",
+	}, []string{"namespace", "name", "bucket"})
+)
+
+func init() {
+	// the metrics are served by the metrics endpoint of controller manager
+	metrics.Registry.MustRegister(
+		storageUsedBytes,
+		storageCapacityBytes,
+		storageDrives,
+		storageBucketSizeBytes,
+		storageBucketObjects,
+	)
+}
+
+// usageBuckets records the buckets exposed for each HarborCluster, so that the bucket metrics can be deleted
+// once the HarborCluster is deleted and its status is gone.
+var (
+	usageBucketsLock sync.Mutex
+	usageBuckets     = map[types.NamespacedName][]string{}
+)
+
+// setUsageMetrics exposes the usage in status as metrics
+func setUsageMetrics(harborCluster *goharborv1.HarborCluster, usage *goharborv1.StorageUsageStatus) {
+	namespace, name := harborCluster.Namespace, harborCluster.Name
+
+	storageUsedBytes.WithLabelValues(namespace, name).Set(float64(usage.UsedBytes))
+	storageCapacityBytes.WithLabelValues(namespace, name).Set(float64(usage.CapacityBytes))
+	storageDrives.WithLabelValues(namespace, name, "online").Set(float64(usage.OnlineDrives))
+	storageDrives.WithLabelValues(namespace, name, "offline").Set(float64(usage.OfflineDrives))
+
+	buckets := make([]string, 0, len(usage.Buckets))
+	for _, bucket := range usage.Buckets {
+		storageBucketSizeBytes.WithLabelValues(namespace, name, bucket.Name).Set(float64(bucket.Size))
+		storageBucketObjects.WithLabelValues(namespace, name, bucket.Name).Set(float64(bucket.Objects))
+		buckets = append(buckets, bucket.Name)
+	}
+
+	usageBucketsLock.Lock()
+	defer usageBucketsLock.Unlock()
+	usageBuckets[types.NamespacedName{Namespace: namespace, Name: name}] = buckets
+}
+
+// DeleteUsageMetrics removes the usage metrics of the HarborCluster,
+// e.g. the storage is no longer inCluster or the HarborCluster is deleted.
+func DeleteUsageMetrics(namespace, name string) {
+	storageUsedBytes.DeleteLabelValues(namespace, name)
+	storageCapacityBytes.DeleteLabelValues(namespace, name)
+	storageDrives.DeleteLabelValues(namespace, name, "online")
+	storageDrives.DeleteLabelValues(namespace, name, "offline")
+
+	usageBucketsLock.Lock()
+	defer usageBucketsLock.Unlock()
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	for _, bucket := range usageBuckets[key] {
+		storageBucketSizeBytes.DeleteLabelValues(namespace, name, bucket)
+		storageBucketObjects.DeleteLabelValues(namespace, name, bucket)
+	}
+	delete(usageBuckets, key)
+}
@@ -0,0 +1,483 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/controllers/k8s"
+	minio "github.com/goharbor/harbor-cluster-operator/controllers/storage/minio/api/v1"
+	"github.com/goharbor/harbor-cluster-operator/lcm"
+	harborv1 "github.com/goharbor/harbor-operator/api/v1alpha1"
+	minv6 "github.com/minio/minio-go/v6"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	MigrationPhaseReadOnly  = "ReadOnly"
+	MigrationPhaseCopying   = "Copying"
+	MigrationPhaseCompleted = "Completed"
+
+	MigrationDirectoryRegistry    = "registry"
+	MigrationDirectoryChartMuseum = "chartmuseum"
+
+	// MigrationBatchSize is the max number of objects copied in a reconcile
+	MigrationBatchSize = 500
+	// MigrationBatchTimeout is the max duration of copying objects in a reconcile
+	MigrationBatchTimeout = time.Minute
+)
+
+// storageLocation is a directory in the storage compatible with S3 API
+type storageLocation struct {
+	endpoint  string
+	secure    bool
+	ca        []byte
+	accessKey string
+	secretKey string
+	roleArn   string
+	region    string
+	bucket    string
+	prefix    string
+}
+
+// isMigrating returns whether the objects should be migrated before switching to the storage kind in spec.
+// A migration starts if the storage secret used by the running harbor belongs to another storage kind.
+func (m *MinIOReconciler) isMigrating() (bool, error) {
+	kind := m.HarborCluster.Spec.Storage.Kind
+	status := m.HarborCluster.Status.StorageMigration
+	if status != nil && status.Target == kind {
+		return status.Phase != MigrationPhaseCompleted, nil
+	}
+
+	harborCR, err := m.getHarborCR()
+	if err != nil || harborCR == nil || harborCR.Spec.Components.Registry == nil {
+		return false, err
+	}
+
+	var secret corev1.Secret
+	err = m.KubeClient.Get(types.NamespacedName{Namespace: m.HarborCluster.Namespace, Name: harborCR.Spec.Components.Registry.StorageSecret}, &secret)
+	if k8serror.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	source := secret.Labels[LabelOfStorageType]
+	if source == "" || source == kind {
+		return false, nil
+	}
+	if !goharborv1.MigratableStorageKinds[source] || !goharborv1.MigratableStorageKinds[kind] {
+		return false, fmt.Errorf("storage can not be migrated from %s to %s", source, kind)
+	}
+
+	now := metav1.Now()
+	m.HarborCluster.Status.StorageMigration = &goharborv1.StorageMigrationStatus{
+		Source:    source,
+		Target:    kind,
+		Phase:     MigrationPhaseReadOnly,
+		Directory: MigrationDirectoryRegistry,
+		Message:   "setting harbor read-only",
+		StartTime: &now,
+	}
+	m.Recorder.Event(m.HarborCluster, corev1.EventTypeNormal, MigratingStorage,
+		fmt.Sprintf(MessageStorageMigrating, source, kind))
+	return true, nil
+}
+
+// Migrate migrates the objects of registry and chart museum to the storage kind in spec. It does:
+// - provision the target storage
+// - set harbor read-only and wait for harbor core to be rolled
+// - copy the objects in batches, each object is verified by checksum and the progress is recorded in status
+// - switch the storage secrets, harbor is reconciled with the new secrets and lifted read-only again
+func (m *MinIOReconciler) Migrate() (*lcm.CRStatus, error) {
+	if m.HarborCluster.Spec.Storage.Kind == inClusterStorage {
+		if status, err := m.reconcileTenant(); status != nil || err != nil {
+			return status, err
+		}
+	} else if reason, err := m.ProbeExternalStorage(); err != nil {
+		return minioNotReadyStatus(reason, err.Error()), err
+	}
+
+	status := m.HarborCluster.Status.StorageMigration
+	switch status.Phase {
+	case MigrationPhaseReadOnly:
+		return m.SetHarborReadOnly()
+	case MigrationPhaseCopying:
+		return m.CopyObjects()
+	}
+
+	return minioMigratingStatus(status.Message), nil
+}
+
+// SetHarborReadOnly sets harbor read-only, so that no objects are written during the migration.
+func (m *MinIOReconciler) SetHarborReadOnly() (*lcm.CRStatus, error) {
+	harborCR, err := m.getHarborCR()
+	if err != nil {
+		return minioNotReadyStatus(MigrateStorageError, err.Error()), err
+	}
+	if harborCR == nil {
+		return m.setMigrationPhase(MigrationPhaseCopying, "copying objects")
+	}
+
+	if !harborCR.Spec.ReadOnly {
+		m.Log.Info("Set harbor read-only to migrate storage.",
+			"namespace", harborCR.Namespace, "name", harborCR.Name)
+		harborCR.Spec.ReadOnly = true
+		if err := m.KubeClient.Update(harborCR); err != nil {
+			return minioNotReadyStatus(MigrateStorageError, err.Error()), err
+		}
+		return minioMigratingStatus("waiting for harbor to be read-only"), nil
+	}
+
+	if harborCR.Status.ObservedGeneration < harborCR.Generation {
+		return minioMigratingStatus("waiting for harbor to be read-only"), nil
+	}
+
+	var core appsv1.Deployment
+	err = m.KubeClient.Get(types.NamespacedName{Namespace: harborCR.Namespace, Name: harborCR.Name + "-core"}, &core)
+	if err != nil && !k8serror.IsNotFound(err) {
+		return minioNotReadyStatus(MigrateStorageError, err.Error()), err
+	}
+	if err == nil && !k8s.IsDeploymentRolledOut(&core) {
+		return minioMigratingStatus("waiting for harbor core to be rolled"), nil
+	}
+
+	return m.setMigrationPhase(MigrationPhaseCopying, "copying objects")
+}
+
+// CopyObjects copies a batch of objects of the directory in status, and moves to the next directory
+// once all the objects are copied.
+func (m *MinIOReconciler) CopyObjects() (*lcm.CRStatus, error) {
+	status := m.HarborCluster.Status.StorageMigration
+
+	source, target, err := m.getMigrationLocations(status.Directory)
+	if err != nil {
+		status.Message = err.Error()
+		return minioNotReadyStatus(MigrateStorageError, err.Error()), err
+	}
+
+	if source != nil && target != nil {
+		done, err := m.copyBatch(source, target)
+		if err != nil {
+			status.Message = err.Error()
+			return minioNotReadyStatus(MigrateStorageError, err.Error()), err
+		}
+		if !done {
+			status.Message = fmt.Sprintf("copying %s objects, %d objects copied", status.Directory, status.CopiedObjects)
+			return minioMigratingStatus(status.Message), nil
+		}
+	}
+
+	if status.Directory == MigrationDirectoryRegistry {
+		status.Directory = MigrationDirectoryChartMuseum
+		status.Checkpoint = ""
+		return minioMigratingStatus("copying chartmuseum objects"), nil
+	}
+
+	now := metav1.Now()
+	status.Checkpoint = ""
+	status.CompletionTime = &now
+	m.Recorder.Event(m.HarborCluster, corev1.EventTypeNormal, MigratedStorage,
+		fmt.Sprintf(MessageStorageMigrated, status.Source, status.Target, status.CopiedObjects))
+	return m.setMigrationPhase(MigrationPhaseCompleted,
+		fmt.Sprintf("%d objects are copied, switching storage secrets", status.CopiedObjects))
+}
+
+// copyBatch copies a page of objects after the checkpoint in the key order, it returns true if all the objects are copied.
+// The page is listed after the checkpoint, so that the objects copied by the previous batches are not listed again.
+func (m *MinIOReconciler) copyBatch(source, target *storageLocation) (bool, error) {
+	status := m.HarborCluster.Status.StorageMigration
+
+	// the object being copied is not interrupted by the timeout, since a blob may take longer than it
+	deadline := time.Now().Add(MigrationBatchTimeout)
+
+	sourceClient, err := newLocationClient(source)
+	if err != nil {
+		return false, err
+	}
+	targetClient, err := newLocationClient(target)
+	if err != nil {
+		return false, err
+	}
+
+	prefix := source.prefix
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	result, err := minv6.Core{Client: sourceClient}.ListObjectsV2(source.bucket, prefix, "", false, "", MigrationBatchSize, status.Checkpoint)
+	if err != nil {
+		return false, err
+	}
+
+	for _, object := range result.Contents {
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		if strings.HasSuffix(object.Key, "/") {
+			status.Checkpoint = object.Key
+			continue
+		}
+
+		key := path.Join(target.prefix, strings.TrimPrefix(object.Key, prefix))
+		if err := copyObject(m.Ctx, sourceClient, targetClient, source.bucket, object.Key, target.bucket, key, object.Size); err != nil {
+			return false, err
+		}
+
+		status.Checkpoint = object.Key
+		status.CopiedObjects++
+		status.CopiedBytes += object.Size
+	}
+
+	return !result.IsTruncated, nil
+}
+
+// copyObject copies the object and verifies the copy by the sha256 checksum of source
+func copyObject(ctx context.Context, source, target *minv6.Client, sourceBucket, sourceKey, targetBucket, targetKey string, size int64) error {
+	object, err := source.GetObjectWithContext(ctx, sourceBucket, sourceKey, minv6.GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	hash := sha256.New()
+	_, err = target.PutObjectWithContext(ctx, targetBucket, targetKey, io.TeeReader(object, hash), size, minv6.PutObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("copy object %s: %v", sourceKey, err)
+	}
+
+	copied, err := target.GetObjectWithContext(ctx, targetBucket, targetKey, minv6.GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+	defer copied.Close()
+
+	verify := sha256.New()
+	if _, err := io.Copy(verify, copied); err != nil {
+		return fmt.Errorf("verify object %s: %v", targetKey, err)
+	}
+	if string(verify.Sum(nil)) != string(hash.Sum(nil)) {
+		return fmt.Errorf("checksum of object %s does not match the source %s", targetKey, sourceKey)
+	}
+	return nil
+}
+
+// getMigrationLocations returns the source and target locations of the directory, they are nil if the directory
+// is not migrated, e.g. chart museum is not enabled. The source is read from the secrets used by the running harbor,
+// and the target is generated from spec.
+func (m *MinIOReconciler) getMigrationLocations(directory string) (*storageLocation, *storageLocation, error) {
+	status := m.HarborCluster.Status.StorageMigration
+
+	harborCR, err := m.getHarborCR()
+	if err != nil || harborCR == nil {
+		return nil, nil, err
+	}
+
+	var targetRegistrySecret, targetChartMuseumSecret *corev1.Secret
+	if status.Target == inClusterStorage {
+		var prefixSuffix string
+		prefixSuffix, err = m.getChartMuseumPrefixSuffix()
+		if err == nil {
+			targetRegistrySecret, targetChartMuseumSecret, err = m.generateInClusterSecret(m.CurrentMinIOCR, prefixSuffix)
+		}
+	} else {
+		targetRegistrySecret, err = m.generateExternalSecret()
+		if err == nil {
+			targetChartMuseumSecret, err = m.generateSecretForChartMuseum()
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	source, err := m.getSecretLocation(status.Source, harborCR.Spec.Components.Registry.StorageSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := m.parseRegistryLocation(status.Target, targetRegistrySecret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if directory == MigrationDirectoryRegistry {
+		return source, target, nil
+	}
+
+	if harborCR.Spec.Components.ChartMuseum == nil || harborCR.Spec.Components.ChartMuseum.StorageSecret == "" ||
+		targetChartMuseumSecret == nil || m.HarborCluster.Spec.ChartMuseum == nil {
+		return nil, nil, nil
+	}
+
+	var sourceChartMuseumSecret corev1.Secret
+	err = m.KubeClient.Get(types.NamespacedName{Namespace: m.HarborCluster.Namespace, Name: harborCR.Spec.Components.ChartMuseum.StorageSecret}, &sourceChartMuseumSecret)
+	if k8serror.IsNotFound(err) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	return parseChartMuseumLocation(source, &sourceChartMuseumSecret), parseChartMuseumLocation(target, targetChartMuseumSecret), nil
+}
+
+func (m *MinIOReconciler) getSecretLocation(kind, name string) (*storageLocation, error) {
+	var secret corev1.Secret
+	err := m.KubeClient.Get(types.NamespacedName{Namespace: m.HarborCluster.Namespace, Name: name}, &secret)
+	if err != nil {
+		return nil, err
+	}
+	return m.parseRegistryLocation(kind, &secret)
+}
+
+// parseRegistryLocation returns the location of registry objects from the storage secret of registry.
+// The registry stores the objects in "<rootdirectory>/docker".
+func (m *MinIOReconciler) parseRegistryLocation(kind string, secret *corev1.Secret) (*storageLocation, error) {
+	var location storageLocation
+	var root string
+
+	switch kind {
+	case inClusterStorage:
+		var data map[string]string
+		if err := json.Unmarshal(secret.Data[s3Storage], &data); err != nil {
+			return nil, err
+		}
+		ca, err := m.getInClusterCA()
+		if err != nil {
+			return nil, err
+		}
+		location = storageLocation{
+			endpoint:  m.getServiceName() + "." + m.HarborCluster.Namespace + ":9000",
+			secure:    ca != nil,
+			ca:        ca,
+			accessKey: data["accesskey"],
+			secretKey: data["secretkey"],
+			region:    data["region"],
+			bucket:    data["bucket"],
+		}
+		root = data["rootdirectory"]
+	case s3Storage:
+		var s3 goharborv1.S3
+		if err := json.Unmarshal(secret.Data[s3Storage], &s3); err != nil {
+			return nil, err
+		}
+		location = storageLocation{
+			accessKey: s3.AccessKey,
+			secretKey: s3.SecretKey,
+			region:    s3.Region,
+			bucket:    s3.Bucket,
+		}
+		// the role is not kept in the secret of registry, only the storage in spec is known
+		if spec := m.HarborCluster.Spec.Storage; spec.Kind == s3Storage && spec.S3 != nil && spec.S3.Bucket == s3.Bucket {
+			location.roleArn = spec.S3.RoleArn
+		}
+		location.endpoint, location.secure = parseS3Endpoint(s3.RegionEndpoint, s3.Secure)
+		root = s3.RootDirectory
+	case ossStorage:
+		var oss goharborv1.Oss
+		if err := json.Unmarshal(secret.Data[ossStorage], &oss); err != nil {
+			return nil, err
+		}
+		location = storageLocation{
+			accessKey: oss.AccessKeyId,
+			secretKey: oss.AccessKeySecret,
+			region:    oss.Region,
+			bucket:    oss.Bucket,
+		}
+		location.endpoint, location.secure = parseS3Endpoint(oss.Endpoint, oss.Secure != "false")
+		root = oss.RootDirectory
+	default:
+		return nil, fmt.Errorf("storage of %s can not be migrated", kind)
+	}
+
+	location.prefix = strings.Trim(path.Join(strings.Trim(root, "/"), "docker"), "/")
+	return &location, nil
+}
+
+// parseChartMuseumLocation returns the location of chart museum objects from the storage secret of chart museum,
+// which is in the same storage of registry.
+func parseChartMuseumLocation(registry *storageLocation, secret *corev1.Secret) *storageLocation {
+	location := *registry
+	switch string(secret.Data["kind"]) {
+	case "alibaba":
+		location.bucket = string(secret.Data["ALIBABA_BUCKET"])
+		location.prefix = string(secret.Data["ALIBABA_PREFIX"])
+	default:
+		location.bucket = string(secret.Data["AMAZON_BUCKET"])
+		location.prefix = string(secret.Data["AMAZON_PREFIX"])
+	}
+	location.prefix = strings.Trim(location.prefix, "/")
+	return &location
+}
+
+// getInClusterCA returns the CA of minIO tenant, it is nil if minIO is served in HTTP
+func (m *MinIOReconciler) getInClusterCA() ([]byte, error) {
+	var tenant minio.Tenant
+	if err := m.KubeClient.Get(m.getMinIONamespacedName(), &tenant); err != nil {
+		return nil, err
+	}
+	if tenant.Spec.ExternalCertSecret == nil {
+		return nil, nil
+	}
+	return m.getMinIOCA()
+}
+
+func newLocationClient(location *storageLocation) (*minv6.Client, error) {
+	creds, err := newS3Credentials(location.accessKey, location.secretKey, location.roleArn, location.region)
+	if err != nil {
+		return nil, err
+	}
+	client, err := minv6.NewWithCredentials(location.endpoint, creds, location.secure, location.region)
+	if err != nil {
+		return nil, err
+	}
+	if location.ca != nil {
+		transport, err := newTLSTransport(location.ca)
+		if err != nil {
+			return nil, err
+		}
+		client.SetCustomTransport(transport)
+	}
+	return client, nil
+}
+
+// getHarborCR returns the Harbor CR, it is nil if harbor has not been provisioned
+func (m *MinIOReconciler) getHarborCR() (*harborv1.Harbor, error) {
+	var harborCR harborv1.Harbor
+	err := m.KubeClient.Get(types.NamespacedName{
+		Namespace: m.HarborCluster.Namespace,
+		Name:      fmt.Sprintf("%s-harbor", m.HarborCluster.Name),
+	}, &harborCR)
+	if k8serror.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &harborCR, nil
+}
+
+func (m *MinIOReconciler) setMigrationPhase(phase, message string) (*lcm.CRStatus, error) {
+	status := m.HarborCluster.Status.StorageMigration
+	status.Phase = phase
+	status.Message = message
+	if phase == MigrationPhaseCompleted {
+		return m.Reconcile()
+	}
+	return minioMigratingStatus(message), nil
+}
+
+func minioMigratingStatus(message string) *lcm.CRStatus {
+	return lcm.New(goharborv1.StorageReady).
+		WithStatus(corev1.ConditionUnknown).
+		WithReason(MigratingStorageReason).
+		WithMessage(message)
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseRegistryLocation(t *testing.T) {
+	s3, _ := json.Marshal(goharborv1.S3{
+		Region:         "us-west-2",
+		Bucket:         "harbor",
+		AccessKey:      "access",
+		SecretKey:      "secret",
+		RegionEndpoint: "https://s3.us-west-2.amazonaws.com",
+		RootDirectory:  "/registry/",
+	})
+	oss, _ := json.Marshal(goharborv1.Oss{
+		AccessKeyId:     "access",
+		AccessKeySecret: "secret",
+		Region:          "oss-cn-hangzhou",
+		Bucket:          "harbor",
+		Endpoint:        "oss-cn-hangzhou.aliyuncs.com",
+		Secure:          "false",
+	})
+
+	cases := []struct {
+		name    string
+		kind    string
+		storage *goharborv1.Storage
+		data    map[string][]byte
+		want    *storageLocation
+		wantErr bool
+	}{
+		{
+			name:    "s3",
+			kind:    s3Storage,
+			storage: &goharborv1.Storage{Kind: fileSystemStorage},
+			data:    map[string][]byte{s3Storage: s3},
+			want: &storageLocation{
+				endpoint:  "s3.us-west-2.amazonaws.com",
+				secure:    true,
+				accessKey: "access",
+				secretKey: "secret",
+				region:    "us-west-2",
+				bucket:    "harbor",
+				prefix:    "registry/docker",
+			},
+		},
+		{
+			name:    "s3 with the role in spec",
+			kind:    s3Storage,
+			storage: &goharborv1.Storage{Kind: s3Storage, S3: &goharborv1.S3{Bucket: "harbor", RoleArn: "arn:aws:iam::123456789012:role/harbor"}},
+			data:    map[string][]byte{s3Storage: s3},
+			want: &storageLocation{
+				endpoint:  "s3.us-west-2.amazonaws.com",
+				secure:    true,
+				accessKey: "access",
+				secretKey: "secret",
+				roleArn:   "arn:aws:iam::123456789012:role/harbor",
+				region:    "us-west-2",
+				bucket:    "harbor",
+				prefix:    "registry/docker",
+			},
+		},
+		{
+			name:    "oss",
+			kind:    ossStorage,
+			storage: &goharborv1.Storage{Kind: fileSystemStorage},
+			data:    map[string][]byte{ossStorage: oss},
+			want: &storageLocation{
+				endpoint:  "oss-cn-hangzhou.aliyuncs.com",
+				secure:    false,
+				accessKey: "access",
+				secretKey: "secret",
+				region:    "oss-cn-hangzhou",
+				bucket:    "harbor",
+				prefix:    "docker",
+			},
+		},
+		{
+			name:    "invalid secret",
+			kind:    s3Storage,
+			storage: &goharborv1.Storage{Kind: fileSystemStorage},
+			data:    map[string][]byte{s3Storage: []byte("{")},
+			wantErr: true,
+		},
+		{
+			name:    "gcs can not be migrated",
+			kind:    gcsStorage,
+			storage: &goharborv1.Storage{Kind: fileSystemStorage},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := &MinIOReconciler{HarborCluster: &goharborv1.HarborCluster{
+				Spec: goharborv1.HarborClusterSpec{Storage: c.storage},
+			}}
+			location, err := m.parseRegistryLocation(c.kind, &corev1.Secret{Data: c.data})
+			if (err != nil) != c.wantErr {
+				t.Fatalf("parseRegistryLocation() error = %v, wantErr %v", err, c.wantErr)
+			}
+			if !reflect.DeepEqual(location, c.want) {
+				t.Errorf("parseRegistryLocation() = %+v, want %+v", location, c.want)
+			}
+		})
+	}
+}
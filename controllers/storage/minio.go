@@ -3,6 +3,8 @@ package storage
 import (
 	"context"
 	"fmt"
+	"path"
+	"strings"
 
 	"github.com/go-logr/logr"
 	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
@@ -30,6 +32,11 @@ const (
 	DefaultExternalSecretSuffix     = "harbor-cluster-storage"
 	ChartMuseumExternalSecretSuffix = "chart-museum-storage"
 
+	// ChartMuseumPrefixSuffix is appended to the bucket or container of registry as the prefix of charts,
+	// LegacyChartMuseumPrefixSuffix is the misspelled one used by the clusters provisioned before.
+	ChartMuseumPrefixSuffix       = "subfolder"
+	LegacyChartMuseumPrefixSuffix = "subfloder"
+
 	DefaultCredsSecret          = "minio-creds"
 	ExternalStorageSecretSuffix = "Secret"
 
@@ -65,7 +72,24 @@ var (
 
 // Reconciler implements the reconcile logic of minIO service
 func (m *MinIOReconciler) Reconcile() (*lcm.CRStatus, error) {
-	var minioCR minio.Tenant
+	migrating, err := m.isMigrating()
+	if err != nil {
+		return minioNotReadyStatus(MigrateStorageError, err.Error()), err
+	}
+	if migrating {
+		return m.Migrate()
+	}
+
+	if m.HarborCluster.Spec.Storage.Kind == fileSystemStorage {
+		if status, err := m.ReconcileFileSystemClaim(); status != nil || err != nil {
+			return status, err
+		}
+	}
+
+	if err := m.ReconcileServiceAccount(); err != nil {
+		return minioNotReadyStatus(ReconcileServiceAccountError, err.Error()), err
+	}
+
 	if m.HarborCluster.Spec.Storage.Kind != inClusterStorage {
 		var exSecret corev1.Secret
 		err := m.KubeClient.Get(m.getExternalSecretNamespacedName(), &exSecret)
@@ -85,13 +109,28 @@ func (m *MinIOReconciler) Reconcile() (*lcm.CRStatus, error) {
 			return m.ExternalUpdate()
 		}
 
-		p := &lcm.Property{
-			Name:  m.HarborCluster.Spec.Storage.Kind + ExternalStorageSecretSuffix,
-			Value: m.getExternalSecretName(),
+		if err := m.ReconcileChartMuseumSecret(); err != nil {
+			return minioNotReadyStatus(UpdateChartMuseumStorageSecretError, err.Error()), err
 		}
-		properties := &lcm.Properties{p}
 
-		return minioReadyStatus(properties), nil
+		return m.externalStorageStatus(), nil
+	}
+
+	// only the external storage is probed
+	m.HarborCluster.Status.StorageProbe = nil
+
+	if status, err := m.reconcileTenant(); status != nil || err != nil {
+		return status, err
+	}
+	return m.ProvisionInClusterSecretAsS3(m.CurrentMinIOCR)
+}
+
+// reconcileTenant provisions, expands and updates the minIO tenant.
+// It returns nil status once the tenant is ready and its buckets are reconciled.
+func (m *MinIOReconciler) reconcileTenant() (*lcm.CRStatus, error) {
+	var minioCR minio.Tenant
+	if err := m.ReconcileCertificate(); err != nil {
+		return minioNotReadyStatus(CreateMinIOCertificateError, err.Error()), err
 	}
 
 	m.DesiredMinIOCR = m.generateMinIOCR()
@@ -105,13 +144,12 @@ func (m *MinIOReconciler) Reconcile() (*lcm.CRStatus, error) {
 
 	m.CurrentMinIOCR = &minioCR
 
-	// TODO remove scale event
-	isScale, err := m.checkMinIOScale()
+	isExpansion, err := m.checkMinIOExpansion()
 	if err != nil {
-		return minioNotReadyStatus(ScaleMinIOError, err.Error()), err
+		return minioNotReadyStatus(ExpandMinIOError, err.Error()), err
 	}
-	if isScale {
-		return m.Scale()
+	if isExpansion {
+		return m.Expand()
 	}
 
 	if m.checkMinIOUpdate() {
@@ -125,33 +163,48 @@ func (m *MinIOReconciler) Reconcile() (*lcm.CRStatus, error) {
 	if isReady {
 		err := m.minioInit()
 		if err != nil {
-			return minioNotReadyStatus(CreateDefaultBucketError, err.Error()), err
+			return minioNotReadyStatus(ReconcileBucketError, err.Error()), err
 		}
-		return m.ProvisionInClusterSecretAsS3(&minioCR)
+		return nil, nil
 	}
 
 	return minioUnknownStatus(), nil
 }
 
 func (m *MinIOReconciler) minioInit() error {
-	accessKey, secretKey, err := m.getCredsFromSecret()
+	client, err := m.newMinIOClient()
 	if err != nil {
 		return err
 	}
-	endpoint := m.getServiceName() + "." + m.HarborCluster.Namespace + ":9000"
+	m.MinioClient = client
+
+	return m.ReconcileBuckets()
+}
 
-	m.MinioClient, err = GetMinioClient(endpoint, string(accessKey), string(secretKey), DefaultRegion, false)
+// newMinIOClient returns the client of minIO tenant with the root credentials
+func (m *MinIOReconciler) newMinIOClient() (*MinioClient, error) {
+	accessKey, secretKey, err := m.getCredsFromSecret()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	endpoint := m.getServiceName() + "." + m.HarborCluster.Namespace + ":9000"
 
-	exists, err := m.MinioClient.IsBucketExists(DefaultBucket)
-	if err != nil || exists {
-		return err
+	client, err := GetMinioClient(endpoint, string(accessKey), string(secretKey), m.getMinIORegion(), m.isMinIOTLSEnabled())
+	if err != nil {
+		return nil, err
 	}
-
-	err = m.MinioClient.CreateBucket(DefaultBucket)
-	return err
+	if m.isMinIOTLSEnabled() {
+		ca, err := m.getMinIOCA()
+		if err != nil {
+			return nil, err
+		}
+		transport, err := newTLSTransport(ca)
+		if err != nil {
+			return nil, err
+		}
+		client.SetTransport(transport)
+	}
+	return client, nil
 }
 
 func (m *MinIOReconciler) checkMinIOUpdate() bool {
@@ -162,35 +215,18 @@ func (m *MinIOReconciler) checkExternalUpdate() bool {
 	return !cmp.Equal(m.DesiredExternalSecret.DeepCopy().Data, m.CurrentExternalSecret.DeepCopy().Data)
 }
 
-func (m *MinIOReconciler) checkMinIOScale() (bool, error) {
-	currentReplicas := m.CurrentMinIOCR.Spec.Zones[0].Servers
-	desiredReplicas := m.HarborCluster.Spec.Storage.InCluster.Spec.Replicas
-	if currentReplicas == desiredReplicas {
-		return false, nil
-	} else if currentReplicas == 1 {
-		return false, fmt.Errorf("not support upgrading from standalone to distributed mode")
-	}
-
-	// MinIO creates erasure-coding sets of 4 to 16 drives per set.
-	// The number of drives you provide in total must be a multiple of one of those numbers.
-	// TODO validate by webhook
-	if desiredReplicas%2 == 0 && desiredReplicas < 16 {
-		return true, nil
-	}
-
-	return false, fmt.Errorf("for distributed mode, supply 4 to 16 drives (should be even)")
-}
-
 func (m *MinIOReconciler) checkMinIOReady() (bool, error) {
 	var minioCR minio.Tenant
 	err := m.KubeClient.Get(m.getMinIONamespacedName(), &minioCR)
 
 	// For different version of minIO have different Status.
 	// Ref https://github.com/minio/operator/commit/d387108ea494cf5cec57628c40d40604ac8d57ec#diff-48972613166d50a2acb9d562e33c5247
-	if minioCR.Status.CurrentState == minio.StatusReady || minioCR.Status.CurrentState == minio.StatusInitialized {
-		return true, err
+	if minioCR.Status.CurrentState != minio.StatusReady && minioCR.Status.CurrentState != minio.StatusInitialized {
+		return false, err
 	}
-	return false, err
+
+	// the servers of appended zones should be available as well
+	return minioCR.Status.AvailableReplicas >= getTotalServers(minioCR.Spec.Zones), err
 }
 
 func (m *MinIOReconciler) getMinIONamespacedName() types.NamespacedName {
@@ -222,6 +258,59 @@ func (m *MinIOReconciler) getChartMuseumSecretName() string {
 	return fmt.Sprintf("%s-%s", m.HarborCluster.Name, ChartMuseumExternalSecretSuffix)
 }
 
+// getMinIOBucket returns the bucket of registry
+func (m *MinIOReconciler) getMinIOBucket() string {
+	if spec := m.HarborCluster.Spec.Storage.InCluster.Spec; spec != nil && spec.Bucket != "" {
+		return spec.Bucket
+	}
+	return DefaultBucket
+}
+
+// getChartMuseumBucket returns the bucket of chart museum, it is the bucket of registry by default
+func (m *MinIOReconciler) getChartMuseumBucket() string {
+	if spec := m.HarborCluster.Spec.Storage.InCluster.Spec; spec != nil && spec.ChartMuseumBucket != "" {
+		return spec.ChartMuseumBucket
+	}
+	return m.getMinIOBucket()
+}
+
+func (m *MinIOReconciler) getMinIORegion() string {
+	if spec := m.HarborCluster.Spec.Storage.InCluster.Spec; spec != nil && spec.Region != "" {
+		return spec.Region
+	}
+	return DefaultRegion
+}
+
+func (m *MinIOReconciler) getMinIORootDirectory() string {
+	if spec := m.HarborCluster.Spec.Storage.InCluster.Spec; spec != nil {
+		return strings.Trim(spec.RootDirectory, "/")
+	}
+	return ""
+}
+
+// getInClusterChartMuseumPrefix returns the prefix of charts in the chart museum bucket.
+// The prefix "<bucket>-<suffix>" is kept for the clusters without root directory,
+// so that the charts stored before are still found.
+func (m *MinIOReconciler) getInClusterChartMuseumPrefix(prefixSuffix string) string {
+	root := m.getMinIORootDirectory()
+	if m.getChartMuseumBucket() != m.getMinIOBucket() {
+		return root
+	}
+	if root == "" {
+		return getChartMuseumPrefix(m.getMinIOBucket(), prefixSuffix)
+	}
+	return path.Join(root, "chartmuseum")
+}
+
+// getMinIOBuckets returns the buckets required by harbor components
+func (m *MinIOReconciler) getMinIOBuckets() []string {
+	buckets := []string{m.getMinIOBucket()}
+	if m.HarborCluster.Spec.ChartMuseum != nil && m.getChartMuseumBucket() != m.getMinIOBucket() {
+		buckets = append(buckets, m.getChartMuseumBucket())
+	}
+	return buckets
+}
+
 func minioNotReadyStatus(reason, message string) *lcm.CRStatus {
 	return &lcm.CRStatus{
 		Condition: goharborv1.HarborClusterCondition{
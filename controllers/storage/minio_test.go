@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"testing"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+)
+
+func TestGetInClusterChartMuseumPrefix(t *testing.T) {
+	cases := []struct {
+		name         string
+		spec         *goharborv1.MinIOSpec
+		prefixSuffix string
+		want         string
+	}{
+		{name: "default bucket", spec: nil, prefixSuffix: ChartMuseumPrefixSuffix, want: "harbor-subfolder"},
+		{name: "legacy suffix", spec: nil, prefixSuffix: LegacyChartMuseumPrefixSuffix, want: "harbor-subfloder"},
+		{name: "shared bucket", spec: &goharborv1.MinIOSpec{Bucket: "registry"}, prefixSuffix: ChartMuseumPrefixSuffix, want: "registry-subfolder"},
+		{name: "shared bucket with root directory", spec: &goharborv1.MinIOSpec{RootDirectory: "/harbor/prod/"}, prefixSuffix: ChartMuseumPrefixSuffix, want: "harbor/prod/chartmuseum"},
+		{name: "chart museum bucket", spec: &goharborv1.MinIOSpec{ChartMuseumBucket: "charts"}, prefixSuffix: ChartMuseumPrefixSuffix, want: ""},
+		{name: "chart museum bucket with root directory", spec: &goharborv1.MinIOSpec{ChartMuseumBucket: "charts", RootDirectory: "prod"}, prefixSuffix: ChartMuseumPrefixSuffix, want: "prod"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := &MinIOReconciler{HarborCluster: &goharborv1.HarborCluster{Spec: goharborv1.HarborClusterSpec{
+				Storage: &goharborv1.Storage{Kind: inClusterStorage, InCluster: &goharborv1.InCluster{Spec: c.spec}},
+			}}}
+			if prefix := m.getInClusterChartMuseumPrefix(c.prefixSuffix); prefix != c.want {
+				t.Errorf("getInClusterChartMuseumPrefix(%q) = %q, want %q", c.prefixSuffix, prefix, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,443 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	iamcredentials "cloud.google.com/go/iam/credentials/apiv1"
+	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/controllers/common"
+	"github.com/golang/protobuf/ptypes"
+	minv6 "github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/credentials"
+	"github.com/ncw/swift"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+	credentialspb "google.golang.org/genproto/googleapis/iam/credentials/v1"
+)
+
+const (
+	// ProbeObjectPrefix is the prefix of the canary object written by the storage probe
+	ProbeObjectPrefix = "harbor-cluster-operator/probe"
+	ProbeTimeout      = 30 * time.Second
+	// StorageProbeInterval is the interval of probing the external storage after the probe passes
+	StorageProbeInterval = 10 * time.Minute
+
+	DefaultAzureRealm = "core.windows.net"
+
+	GcsReadWriteScp = "https://www.googleapis.com/auth/devstorage.read_write"
+	// GcsCloudPlatformScp is the scope of the operator credentials to impersonate the google service account
+	GcsCloudPlatformScp = "https://www.googleapis.com/auth/cloud-platform"
+
+	// STSRoleSessionName is the session name of the IAM role assumed by the operator
+	STSRoleSessionName = "harbor-cluster-operator"
+)
+
+// errStorageUnverified means the storage uses a workload identity the operator can not act as,
+// the storage can not be probed or migrated by the operator then.
+var errStorageUnverified = errors.New("the operator can not act as the workload identity of storage")
+
+// Prober checks the capabilities of the external storage required by harbor
+type Prober interface {
+	BucketExists(ctx context.Context) (bool, error)
+	PutObject(ctx context.Context, name string, data []byte) error
+	GetObject(ctx context.Context, name string) ([]byte, error)
+	DeleteObject(ctx context.Context, name string) error
+	Close() error
+}
+
+// ProbeExternalStorage checks the bucket or container exists, and a canary object can be written,
+// read and deleted. It returns the failing capability as the reason.
+// The probe acts as the workload identity of storage, it is skipped with errStorageUnverified if the operator
+// can not assume the IAM role or impersonate the google service account.
+func (m *MinIOReconciler) ProbeExternalStorage() (string, error) {
+	ctx, cancel := context.WithTimeout(m.Ctx, ProbeTimeout)
+	defer cancel()
+
+	prober, root, err := m.newProber(ctx)
+	if errors.Is(err, errStorageUnverified) {
+		return StorageUnverifiedReason, err
+	} else if err != nil {
+		return StorageConnectError, err
+	}
+	defer prober.Close()
+
+	exists, err := prober.BucketExists(ctx)
+	if err != nil {
+		return StorageConnectError, err
+	}
+	if !exists {
+		return StorageBucketNotFoundError, fmt.Errorf("bucket or container of %s storage does not exist", m.HarborCluster.Spec.Storage.Kind)
+	}
+
+	name := strings.TrimPrefix(path.Join(root, ProbeObjectPrefix, string(m.HarborCluster.UID)), "/")
+	data := []byte(common.RandomString(32, common.LowerStringRandomType))
+
+	if err := prober.PutObject(ctx, name, data); err != nil {
+		return StoragePutObjectError, err
+	}
+
+	actual, err := prober.GetObject(ctx, name)
+	if err != nil {
+		return StorageGetObjectError, err
+	}
+	if !bytes.Equal(actual, data) {
+		return StorageGetObjectError, fmt.Errorf("object %s read from storage does not match the written one", name)
+	}
+
+	if err := prober.DeleteObject(ctx, name); err != nil {
+		return StorageDeleteObjectError, err
+	}
+
+	return "", nil
+}
+
+// newProber returns the prober of the external storage kind, and the root directory in the bucket
+func (m *MinIOReconciler) newProber(ctx context.Context) (Prober, string, error) {
+	storage := m.HarborCluster.Spec.Storage
+	switch storage.Kind {
+	case s3Storage:
+		creds, err := newS3Credentials(storage.S3.AccessKey, storage.S3.SecretKey, storage.S3.RoleArn, storage.S3.Region)
+		if err != nil {
+			return nil, "", err
+		}
+		prober, err := newS3Prober(storage.S3.RegionEndpoint, storage.S3.Region, storage.S3.Bucket, creds, storage.S3.Secure)
+		return prober, storage.S3.RootDirectory, err
+	case ossStorage:
+		secure := storage.Oss.Secure != "false"
+		prober, err := newS3Prober(storage.Oss.Endpoint, storage.Oss.Region, storage.Oss.Bucket,
+			credentials.NewStaticV4(storage.Oss.AccessKeyId, storage.Oss.AccessKeySecret, ""), secure)
+		return prober, storage.Oss.RootDirectory, err
+	case azureStorage:
+		prober, err := newAzureProber(storage.Azure.AccountName, storage.Azure.AccountKey, storage.Azure.Container, storage.Azure.Realm)
+		return prober, "", err
+	case gcsStorage:
+		prober, err := newGcsProber(ctx, storage.Gcs.Bucket, storage.Gcs.EncodedKey, storage.Gcs.ServiceAccount)
+		return prober, storage.Gcs.RootDirectory, err
+	case swiftStorage:
+		return newSwiftProber(storage.Swift), storage.Swift.Prefix, nil
+	default:
+		return nil, "", fmt.Errorf(NotSupportType)
+	}
+}
+
+// s3Prober probes the storage compatible with S3 API, it is used by s3 and oss.
+type s3Prober struct {
+	client *minv6.Client
+	bucket string
+}
+
+func newS3Prober(endpoint, region, bucket string, creds *credentials.Credentials, secure bool) (*s3Prober, error) {
+	host, secure := parseS3Endpoint(endpoint, secure)
+	client, err := minv6.NewWithCredentials(host, creds, secure, region)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Prober{client: client, bucket: bucket}, nil
+}
+
+// newS3Credentials returns the static credentials of the keys. The keys are empty with IRSA, the IAM role is
+// assumed by the keys of the operator in environments then. errStorageUnverified is returned if the operator
+// has no keys, the identity of the operator itself is never used for the storage of harbor.
+func newS3Credentials(accessKey, secretKey, roleArn, region string) (*credentials.Credentials, error) {
+	if accessKey != "" || secretKey != "" {
+		return credentials.NewStaticV4(accessKey, secretKey, ""), nil
+	}
+	if roleArn == "" {
+		return nil, errors.New("s3 storage requires accesskey and secretkey, or rolearn")
+	}
+
+	env := &credentials.EnvAWS{}
+	operator, err := env.Retrieve()
+	if err != nil || operator.AccessKeyID == "" || operator.SecretAccessKey == "" {
+		return nil, errStorageUnverified
+	}
+
+	stsEndpoint := "https://sts.amazonaws.com"
+	if region != "" {
+		stsEndpoint = fmt.Sprintf("https://sts.%s.amazonaws.com", region)
+	}
+	return credentials.NewSTSAssumeRole(stsEndpoint, credentials.STSAssumeRoleOptions{
+		AccessKey:       operator.AccessKeyID,
+		SecretKey:       operator.SecretAccessKey,
+		Location:        region,
+		RoleARN:         roleArn,
+		RoleSessionName: STSRoleSessionName,
+	})
+}
+
+// parseS3Endpoint returns the host of endpoint, the scheme of endpoint overrides secure if it is an URL
+func parseS3Endpoint(endpoint string, secure bool) (string, bool) {
+	if endpoint == "" {
+		return "s3.amazonaws.com", secure
+	}
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host, u.Scheme == "https"
+	}
+	return endpoint, secure
+}
+
+func (p *s3Prober) BucketExists(ctx context.Context) (bool, error) {
+	return p.client.BucketExistsWithContext(ctx, p.bucket)
+}
+
+func (p *s3Prober) PutObject(ctx context.Context, name string, data []byte) error {
+	_, err := p.client.PutObjectWithContext(ctx, p.bucket, name, bytes.NewReader(data), int64(len(data)), minv6.PutObjectOptions{})
+	return err
+}
+
+func (p *s3Prober) GetObject(ctx context.Context, name string) ([]byte, error) {
+	object, err := p.client.GetObjectWithContext(ctx, p.bucket, name, minv6.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+	return ioutil.ReadAll(object)
+}
+
+func (p *s3Prober) DeleteObject(ctx context.Context, name string) error {
+	return p.client.RemoveObject(p.bucket, name)
+}
+
+func (p *s3Prober) Close() error {
+	return nil
+}
+
+// azureProber probes the azure blob container with the shared key.
+type azureProber struct {
+	container azblob.ContainerURL
+}
+
+func newAzureProber(account, accountKey, container, realm string) (*azureProber, error) {
+	credential, err := azblob.NewSharedKeyCredential(account, accountKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid azure account key: %v", err)
+	}
+	if realm == "" {
+		realm = DefaultAzureRealm
+	}
+	u, err := url.Parse(fmt.Sprintf("https://%s.blob.%s/%s", account, realm, container))
+	if err != nil {
+		return nil, err
+	}
+	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{
+		Retry: azblob.RetryOptions{TryTimeout: ProbeTimeout},
+	})
+	return &azureProber{container: azblob.NewContainerURL(*u, pipeline)}, nil
+}
+
+func (p *azureProber) BucketExists(ctx context.Context) (bool, error) {
+	_, err := p.container.GetProperties(ctx, azblob.LeaseAccessConditions{})
+	if serr, ok := err.(azblob.StorageError); ok && serr.ServiceCode() == azblob.ServiceCodeContainerNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (p *azureProber) PutObject(ctx context.Context, name string, data []byte) error {
+	_, err := p.container.NewBlockBlobURL(name).Upload(ctx, bytes.NewReader(data), azblob.BlobHTTPHeaders{},
+		azblob.Metadata{}, azblob.BlobAccessConditions{})
+	return err
+}
+
+func (p *azureProber) GetObject(ctx context.Context, name string) ([]byte, error) {
+	resp, err := p.container.NewBlobURL(name).Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		return nil, err
+	}
+	body := resp.Body(azblob.RetryReaderOptions{})
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+func (p *azureProber) DeleteObject(ctx context.Context, name string) error {
+	_, err := p.container.NewBlobURL(name).Delete(ctx, azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{})
+	return err
+}
+
+func (p *azureProber) Close() error {
+	return nil
+}
+
+// gcsProber probes the google cloud storage bucket.
+type gcsProber struct {
+	bucket *storage.BucketHandle
+	client *storage.Client
+	// iam issues the access tokens of the impersonated service account, it is nil with the encoded key
+	iam *iamcredentials.IamCredentialsClient
+}
+
+// newGcsProber returns the prober acting as the encoded key or the google service account,
+// errStorageUnverified is returned if neither is set, the identity of the operator itself is never
+// used for the storage of harbor.
+func newGcsProber(ctx context.Context, bucket, encodedKey, serviceAccount string) (*gcsProber, error) {
+	switch {
+	case encodedKey != "":
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gcs encoded key: %v", err)
+		}
+		client, err := storage.NewClient(ctx, option.WithCredentialsJSON(key), option.WithScopes(GcsReadWriteScp))
+		if err != nil {
+			return nil, err
+		}
+		return &gcsProber{bucket: client.Bucket(bucket), client: client}, nil
+	case serviceAccount != "":
+		iam, err := newIamCredentialsClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		source := &impersonatedTokenSource{
+			ctx:            ctx,
+			iam:            iam,
+			serviceAccount: serviceAccount,
+			scopes:         []string{GcsReadWriteScp},
+		}
+		client, err := storage.NewClient(ctx, option.WithTokenSource(oauth2.ReuseTokenSource(nil, source)))
+		if err != nil {
+			iam.Close()
+			return nil, err
+		}
+		return &gcsProber{bucket: client.Bucket(bucket), client: client, iam: iam}, nil
+	default:
+		return nil, errStorageUnverified
+	}
+}
+
+// newIamCredentialsClient returns the IAM credentials client with the default credentials of the operator,
+// which must be granted the role "Service Account Token Creator" on the service account.
+// errStorageUnverified is returned if the operator has no google credentials.
+func newIamCredentialsClient(ctx context.Context) (*iamcredentials.IamCredentialsClient, error) {
+	creds, err := google.FindDefaultCredentials(ctx, GcsCloudPlatformScp)
+	if err != nil {
+		return nil, errStorageUnverified
+	}
+	return iamcredentials.NewIamCredentialsClient(ctx, option.WithCredentials(creds))
+}
+
+// impersonatedTokenSource issues the access tokens of the google service account
+type impersonatedTokenSource struct {
+	ctx            context.Context
+	iam            *iamcredentials.IamCredentialsClient
+	serviceAccount string
+	scopes         []string
+}
+
+func (s *impersonatedTokenSource) Token() (*oauth2.Token, error) {
+	resp, err := s.iam.GenerateAccessToken(s.ctx, &credentialspb.GenerateAccessTokenRequest{
+		Name:  fmt.Sprintf("projects/-/serviceAccounts/%s", s.serviceAccount),
+		Scope: s.scopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	expiry, err := ptypes.Timestamp(resp.ExpireTime)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{AccessToken: resp.AccessToken, Expiry: expiry}, nil
+}
+
+func (p *gcsProber) BucketExists(ctx context.Context) (bool, error) {
+	_, err := p.bucket.Attrs(ctx)
+	if err == storage.ErrBucketNotExist {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (p *gcsProber) PutObject(ctx context.Context, name string, data []byte) error {
+	w := p.bucket.Object(name).NewWriter(ctx)
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (p *gcsProber) GetObject(ctx context.Context, name string) ([]byte, error) {
+	r, err := p.bucket.Object(name).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func (p *gcsProber) DeleteObject(ctx context.Context, name string) error {
+	return p.bucket.Object(name).Delete(ctx)
+}
+
+func (p *gcsProber) Close() error {
+	err := p.client.Close()
+	if p.iam != nil {
+		if ierr := p.iam.Close(); err == nil {
+			err = ierr
+		}
+	}
+	return err
+}
+
+// swiftProber probes the openstack swift container.
+type swiftProber struct {
+	conn      *swift.Connection
+	container string
+}
+
+func newSwiftProber(spec *goharborv1.Swift) *swiftProber {
+	conn := &swift.Connection{
+		UserName:     spec.Username,
+		ApiKey:       spec.Password,
+		AuthUrl:      spec.Authurl,
+		Region:       spec.Region,
+		Tenant:       spec.Tenant,
+		TenantId:     spec.TenantId,
+		Domain:       spec.Domain,
+		DomainId:     spec.DomainId,
+		TrustId:      spec.TrustId,
+		AuthVersion:  spec.AuthVersion,
+		EndpointType: swift.EndpointType(spec.EndpointType),
+		Timeout:      ProbeTimeout,
+	}
+	if spec.InsecureSkipVerify {
+		conn.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	return &swiftProber{conn: conn, container: spec.Container}
+}
+
+func (p *swiftProber) BucketExists(ctx context.Context) (bool, error) {
+	_, _, err := p.conn.Container(p.container)
+	if err == swift.ContainerNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (p *swiftProber) PutObject(ctx context.Context, name string, data []byte) error {
+	return p.conn.ObjectPutBytes(p.container, name, data, "application/octet-stream")
+}
+
+func (p *swiftProber) GetObject(ctx context.Context, name string) ([]byte, error) {
+	return p.conn.ObjectGetBytes(p.container, name)
+}
+
+func (p *swiftProber) DeleteObject(ctx context.Context, name string) error {
+	return p.conn.ObjectDelete(p.container, name)
+}
+
+func (p *swiftProber) Close() error {
+	return nil
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestParseS3Endpoint(t *testing.T) {
+	cases := []struct {
+		name       string
+		endpoint   string
+		secure     bool
+		wantHost   string
+		wantSecure bool
+	}{
+		{name: "default endpoint", endpoint: "", secure: true, wantHost: "s3.amazonaws.com", wantSecure: true},
+		{name: "host keeps secure", endpoint: "minio.example.com:9000", secure: false, wantHost: "minio.example.com:9000", wantSecure: false},
+		{name: "https url", endpoint: "https://s3.us-west-2.amazonaws.com", secure: false, wantHost: "s3.us-west-2.amazonaws.com", wantSecure: true},
+		{name: "http url", endpoint: "http://minio.example.com:9000", secure: true, wantHost: "minio.example.com:9000", wantSecure: false},
+		{name: "url with path", endpoint: "https://oss-cn-hangzhou.aliyuncs.com/", secure: false, wantHost: "oss-cn-hangzhou.aliyuncs.com", wantSecure: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			host, secure := parseS3Endpoint(c.endpoint, c.secure)
+			if host != c.wantHost || secure != c.wantSecure {
+				t.Errorf("parseS3Endpoint(%q, %v) = %q, %v, want %q, %v", c.endpoint, c.secure, host, secure, c.wantHost, c.wantSecure)
+			}
+		})
+	}
+}
+
+func TestNewGcsProber(t *testing.T) {
+	cases := []struct {
+		name           string
+		encodedKey     string
+		serviceAccount string
+		wantErr        error
+	}{
+		{name: "neither key nor service account", wantErr: errStorageUnverified},
+		{name: "invalid encoded key", encodedKey: "not base64!"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			prober, err := newGcsProber(context.Background(), "harbor", c.encodedKey, c.serviceAccount)
+			if err == nil {
+				prober.Close()
+				t.Fatalf("newGcsProber() error = nil, want error")
+			}
+			if c.wantErr != nil && !errors.Is(err, c.wantErr) {
+				t.Errorf("newGcsProber() error = %v, want %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewAzureProber(t *testing.T) {
+	cases := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{name: "valid account key", key: base64.StdEncoding.EncodeToString([]byte("secret"))},
+		{name: "invalid account key", key: "not base64!", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := newAzureProber("harbor", c.key, "registry", "")
+			if (err != nil) != c.wantErr {
+				t.Errorf("newAzureProber() error = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
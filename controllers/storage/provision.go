@@ -1,10 +1,12 @@
 package storage
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
 	"net/url"
+	"path"
 	"reflect"
 	"strings"
 
@@ -17,21 +19,31 @@ import (
 	k8serror "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func (m *MinIOReconciler) ProvisionInClusterSecretAsS3(minioInstamnce *minio.Tenant) (*lcm.CRStatus, error) {
-	inClusterSecret, chartMuseumSecret, err := m.generateInClusterSecret(minioInstamnce)
+	if err := m.ReconcileCABundle(); err != nil {
+		return minioNotReadyStatus(CreateMinIOCABundleError, err.Error()), err
+	}
+
+	prefixSuffix, err := m.getChartMuseumPrefixSuffix()
+	if err != nil {
+		return minioNotReadyStatus(CreateChartMuseumStorageSecretError, err.Error()), err
+	}
+
+	inClusterSecret, chartMuseumSecret, err := m.generateInClusterSecret(minioInstamnce, prefixSuffix)
 	if err != nil {
 		return minioNotReadyStatus(GetMinIOSecretError, err.Error()), err
 	}
-	err = m.KubeClient.Create(inClusterSecret)
-	if err != nil && !k8serror.IsAlreadyExists(err) {
+	err = m.applySecret(inClusterSecret)
+	if err != nil {
 		return minioNotReadyStatus(GetMinIOSecretError, err.Error()), err
 	}
 
-	err = m.KubeClient.Create(chartMuseumSecret)
-	if err != nil && !k8serror.IsAlreadyExists(err) {
+	err = m.applySecret(chartMuseumSecret)
+	if err != nil {
 		return minioNotReadyStatus(CreateChartMuseumStorageSecretError, err.Error()), err
 	}
 
@@ -44,7 +56,7 @@ func (m *MinIOReconciler) ProvisionInClusterSecretAsS3(minioInstamnce *minio.Ten
 	return minioReadyStatus(properties), nil
 }
 
-func (m *MinIOReconciler) generateInClusterSecret(minioInstance *minio.Tenant) (inClusterSecret *corev1.Secret, chartMuseumSecret *corev1.Secret, err error) {
+func (m *MinIOReconciler) generateInClusterSecret(minioInstance *minio.Tenant, prefixSuffix string) (inClusterSecret *corev1.Secret, chartMuseumSecret *corev1.Secret, err error) {
 	labels := m.getLabels()
 	labels[LabelOfStorageType] = inClusterStorage
 	accessKey, secretKey, err := m.getCredsFromSecret()
@@ -63,8 +75,14 @@ func (m *MinIOReconciler) generateInClusterSecret(minioInstance *minio.Tenant) (
 
 		endpoint = schema + "://" + host
 		secure = "true"
-		skipverify = "true"
-
+		// the certificate of ingress is verified with the CA bundle mounted to registry and chart museum
+		if !m.isMinIOTLSEnabled() {
+			skipverify = "true"
+		}
+	} else if m.isMinIOTLSEnabled() {
+		// the CA bundle of minIO is mounted to registry and chart museum by the storage injector
+		endpoint = fmt.Sprintf("https://%s.%s.svc:%s", m.getServiceName(), m.HarborCluster.Namespace, "9000")
+		secure = "true"
 	} else {
 		endpoint = fmt.Sprintf("http://%s.%s.svc:%s", m.getServiceName(), m.HarborCluster.Namespace, "9000")
 	}
@@ -72,14 +90,17 @@ func (m *MinIOReconciler) generateInClusterSecret(minioInstance *minio.Tenant) (
 	data := map[string]string{
 		"accesskey":      string(accessKey),
 		"secretkey":      string(secretKey),
-		"region":         DefaultRegion,
-		"bucket":         DefaultBucket,
+		"region":         m.getMinIORegion(),
+		"bucket":         m.getMinIOBucket(),
 		"regionendpoint": endpoint,
 		"secure":         secure,
 		"skipverify":     skipverify,
 		"encrypt":        "false",
 		"v4auth":         "false",
 	}
+	if root := m.getMinIORootDirectory(); root != "" {
+		data["rootdirectory"] = "/" + root
+	}
 	dataJson, _ := json.Marshal(&data)
 	inClusterSecret = &corev1.Secret{
 		TypeMeta: metav1.TypeMeta{
@@ -120,11 +141,10 @@ func (m *MinIOReconciler) generateInClusterSecret(minioInstance *minio.Tenant) (
 			"kind":                  []byte("amazon"),
 			"AWS_ACCESS_KEY_ID":     accessKey,
 			"AWS_SECRET_ACCESS_KEY": secretKey,
-			// use same bucket.
-			"AMAZON_BUCKET":   []byte(DefaultBucket),
-			"AMAZON_PREFIX":   []byte(fmt.Sprintf("%s-subfloder", DefaultBucket)),
-			"AMAZON_REGION":   []byte(DefaultRegion),
-			"AMAZON_ENDPOINT": []byte(endpoint),
+			"AMAZON_BUCKET":         []byte(m.getChartMuseumBucket()),
+			"AMAZON_PREFIX":         []byte(m.getInClusterChartMuseumPrefix(prefixSuffix)),
+			"AMAZON_REGION":         []byte(m.getMinIORegion()),
+			"AMAZON_ENDPOINT":       []byte(endpoint),
 		},
 	}
 
@@ -138,27 +158,15 @@ func (m *MinIOReconciler) ProvisionExternalStorage() (*lcm.CRStatus, error) {
 	}
 
 	err = m.KubeClient.Create(exSecret)
-	if err != nil {
-		minioNotReadyStatus(CreateExternalSecretError, err.Error())
-	}
-
-	chartMuseumSecret, err := m.generateSecretForChartMuseum()
-	if err != nil {
-		return minioNotReadyStatus(GenerateChartMuseumStorageSecretError, err.Error()), err
-	}
-
-	err = m.KubeClient.Create(chartMuseumSecret)
-	if err != nil {
-		minioNotReadyStatus(CreateChartMuseumStorageSecretError, err.Error())
+	if err != nil && !k8serror.IsAlreadyExists(err) {
+		return minioNotReadyStatus(CreateExternalSecretError, err.Error()), err
 	}
 
-	properties := &lcm.Properties{}
-	properties.Add(m.HarborCluster.Spec.Storage.Kind+ExternalStorageSecretSuffix, m.getExternalSecretName())
-	if m.HarborCluster.Spec.ChartMuseum != nil {
-		properties.Add(lcm.ChartMuseumSecretForStorage, chartMuseumSecret.Name)
+	if err := m.ReconcileChartMuseumSecret(); err != nil {
+		return minioNotReadyStatus(CreateChartMuseumStorageSecretError, err.Error()), err
 	}
 
-	return minioReadyStatus(properties), nil
+	return m.externalStorageStatus(), nil
 }
 
 func (m *MinIOReconciler) generateExternalSecret() (exSecret *corev1.Secret, err error) {
@@ -180,6 +188,9 @@ func (m *MinIOReconciler) generateExternalSecret() (exSecret *corev1.Secret, err
 	case ossStorage:
 		labels[LabelOfStorageType] = ossStorage
 		exSecret, err = m.generateOssSecret(labels)
+	case fileSystemStorage:
+		labels[LabelOfStorageType] = fileSystemStorage
+		exSecret, err = m.generateFileSystemSecret(labels)
 	default:
 		return exSecret, fmt.Errorf(NotSupportType)
 	}
@@ -187,8 +198,13 @@ func (m *MinIOReconciler) generateExternalSecret() (exSecret *corev1.Secret, err
 	return exSecret, err
 }
 
+// generateS3Secret returns the s3 storage secret of registry, the keys are left empty with IRSA,
+// so that registry uses the credentials of the IAM role.
 func (m *MinIOReconciler) generateS3Secret(labels map[string]string) (*corev1.Secret, error) {
-	dataJson, err := json.Marshal(m.HarborCluster.Spec.Storage.S3)
+	s3 := *m.HarborCluster.Spec.Storage.S3
+	// the role is bound to the service account, it is not an option of the storage driver
+	s3.RoleArn = ""
+	dataJson, err := json.Marshal(s3)
 	if err != nil {
 		return nil, err
 	}
@@ -217,19 +233,40 @@ func (m *MinIOReconciler) generateSecretForChartMuseum() (secret *corev1.Secret,
 	if m.HarborCluster.Spec.ChartMuseum == nil {
 		return secret, nil
 	}
+	prefixSuffix, err := m.getChartMuseumPrefixSuffix()
+	if err != nil {
+		return secret, err
+	}
 	labels := m.getLabels()
 	switch m.HarborCluster.Spec.Storage.Kind {
+	case azureStorage:
+		labels[LabelOfStorageType] = azureStorage
+		secret = m.generateAzureSecretForChartMuseum(labels, prefixSuffix)
+	case gcsStorage:
+		labels[LabelOfStorageType] = gcsStorage
+		secret = m.generateGcsSecretForChartMuseum(labels, prefixSuffix)
 	case s3Storage:
 		labels[LabelOfStorageType] = s3Storage
-		secret = m.generateS3SecretForChartMuseum(labels)
+		secret = m.generateS3SecretForChartMuseum(labels, prefixSuffix)
+	case swiftStorage:
+		labels[LabelOfStorageType] = swiftStorage
+		secret = m.generateSwiftSecretForChartMuseum(labels, prefixSuffix)
+	case ossStorage:
+		labels[LabelOfStorageType] = ossStorage
+		secret = m.generateOssSecretForChartMuseum(labels, prefixSuffix)
+	case fileSystemStorage:
+		labels[LabelOfStorageType] = fileSystemStorage
+		secret = m.generateFileSystemSecretForChartMuseum(labels)
 	default:
 		return secret, fmt.Errorf(NotSupportType)
 	}
 	return secret, nil
 }
 
-func (m *MinIOReconciler) generateS3SecretForChartMuseum(labels map[string]string) *corev1.Secret {
-	return &corev1.Secret{
+// newChartMuseumSecret returns the storage secret of chart museum. Harbor operator sets the kind
+// to STORAGE, and exposes the other keys both as they are and prefixed with "STORAGE_".
+func (m *MinIOReconciler) newChartMuseumSecret(labels map[string]string, kind string, data map[string]string) *corev1.Secret {
+	secret := &corev1.Secret{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "v1",
 			Kind:       "Secret",
@@ -245,15 +282,145 @@ func (m *MinIOReconciler) generateS3SecretForChartMuseum(labels map[string]strin
 		},
 		Type: corev1.SecretTypeOpaque,
 		Data: map[string][]byte{
-			"kind":                  []byte("amazon"),
-			"AWS_ACCESS_KEY_ID":     []byte(m.HarborCluster.Spec.Storage.S3.AccessKey),
-			"AWS_SECRET_ACCESS_KEY": []byte(m.HarborCluster.Spec.Storage.S3.SecretKey),
-			"AMAZON_BUCKET":         []byte(m.HarborCluster.Spec.Storage.S3.Bucket),
-			"AMAZON_PREFIX":         []byte(fmt.Sprintf("%s-subfloder", m.HarborCluster.Spec.Storage.S3.Bucket)),
-			"AMAZON_REGION":         []byte(m.HarborCluster.Spec.Storage.S3.Region),
-			"AMAZON_ENDPOINT":       []byte(m.HarborCluster.Spec.Storage.S3.RegionEndpoint),
+			"kind": []byte(kind),
+		},
+	}
+	for k, v := range data {
+		if v != "" {
+			secret.Data[k] = []byte(v)
+		}
+	}
+	return secret
+}
+
+func (m *MinIOReconciler) generateS3SecretForChartMuseum(labels map[string]string, prefixSuffix string) *corev1.Secret {
+	s3 := m.HarborCluster.Spec.Storage.S3
+	return m.newChartMuseumSecret(labels, "amazon", map[string]string{
+		"AWS_ACCESS_KEY_ID":     s3.AccessKey,
+		"AWS_SECRET_ACCESS_KEY": s3.SecretKey,
+		"AMAZON_BUCKET":         s3.Bucket,
+		"AMAZON_PREFIX":         getChartMuseumPrefix(s3.Bucket, prefixSuffix),
+		"AMAZON_REGION":         s3.Region,
+		"AMAZON_ENDPOINT":       s3.RegionEndpoint,
+	})
+}
+
+func (m *MinIOReconciler) generateAzureSecretForChartMuseum(labels map[string]string, prefixSuffix string) *corev1.Secret {
+	azure := m.HarborCluster.Spec.Storage.Azure
+	return m.newChartMuseumSecret(labels, "microsoft", map[string]string{
+		"AZURE_STORAGE_ACCOUNT":    azure.AccountName,
+		"AZURE_STORAGE_ACCESS_KEY": azure.AccountKey,
+		"MICROSOFT_CONTAINER":      azure.Container,
+		"MICROSOFT_PREFIX":         getChartMuseumPrefix(azure.Container, prefixSuffix),
+	})
+}
+
+// generateGcsSecretForChartMuseum returns the google storage secret of chart museum.
+// The secret is only exposed as environments by harbor operator, so the key file is mounted by the storage injector
+// and GOOGLE_APPLICATION_CREDENTIALS points to it. Chart museum uses the application default credentials
+// of Workload Identity if there is no encoded key.
+func (m *MinIOReconciler) generateGcsSecretForChartMuseum(labels map[string]string, prefixSuffix string) *corev1.Secret {
+	gcs := m.HarborCluster.Spec.Storage.Gcs
+	data := map[string]string{
+		"GOOGLE_BUCKET": gcs.Bucket,
+		"GOOGLE_PREFIX": getChartMuseumPrefix(gcs.Bucket, prefixSuffix),
+	}
+	if gcs.EncodedKey != "" {
+		data["GOOGLE_APPLICATION_CREDENTIALS"] = path.Join(GcsKeyMountPath, GcsKeyFile)
+	}
+	return m.newChartMuseumSecret(labels, "google", data)
+}
+
+// ReconcileGcsKeySecret keeps the decoded key of gcs storage in the secret mounted into chart museum pods,
+// the secret is deleted once chart museum does not use the encoded key.
+func (m *MinIOReconciler) ReconcileGcsKeySecret() error {
+	storage := m.HarborCluster.Spec.Storage
+	if storage.Kind != gcsStorage || storage.Gcs == nil || storage.Gcs.EncodedKey == "" || m.HarborCluster.Spec.ChartMuseum == nil {
+		var current corev1.Secret
+		err := m.KubeClient.Get(types.NamespacedName{Namespace: m.HarborCluster.Namespace, Name: gcsKeySecretName(m.HarborCluster.Name)}, &current)
+		if k8serror.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return m.KubeClient.Delete(&current)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(storage.Gcs.EncodedKey)
+	if err != nil {
+		return errors.Wrap(err, "decode gcs encodedkey")
+	}
+
+	return m.applySecret(&corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        gcsKeySecretName(m.HarborCluster.Name),
+			Namespace:   m.HarborCluster.Namespace,
+			Labels:      m.getLabels(),
+			Annotations: m.generateAnnotations(),
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(m.HarborCluster, goharborv1.HarborClusterGVK),
+			},
 		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			GcsKeyFile: key,
+		},
+	})
+}
+
+func (m *MinIOReconciler) generateSwiftSecretForChartMuseum(labels map[string]string, prefixSuffix string) *corev1.Secret {
+	swift := m.HarborCluster.Spec.Storage.Swift
+	return m.newChartMuseumSecret(labels, "openstack", map[string]string{
+		"OS_AUTH_URL":         swift.Authurl,
+		"OS_USERNAME":         swift.Username,
+		"OS_PASSWORD":         swift.Password,
+		"OS_TENANT_NAME":      swift.Tenant,
+		"OS_TENANT_ID":        swift.TenantId,
+		"OS_DOMAIN_NAME":      swift.Domain,
+		"OS_DOMAIN_ID":        swift.DomainId,
+		"OPENSTACK_CONTAINER": swift.Container,
+		"OPENSTACK_PREFIX":    getChartMuseumPrefix(swift.Container, prefixSuffix),
+		"OPENSTACK_REGION":    swift.Region,
+	})
+}
+
+func (m *MinIOReconciler) generateOssSecretForChartMuseum(labels map[string]string, prefixSuffix string) *corev1.Secret {
+	oss := m.HarborCluster.Spec.Storage.Oss
+	return m.newChartMuseumSecret(labels, "alibaba", map[string]string{
+		"ALIBABA_CLOUD_ACCESS_KEY_ID":     oss.AccessKeyId,
+		"ALIBABA_CLOUD_ACCESS_KEY_SECRET": oss.AccessKeySecret,
+		"ALIBABA_BUCKET":                  oss.Bucket,
+		"ALIBABA_PREFIX":                  getChartMuseumPrefix(oss.Bucket, prefixSuffix),
+		"ALIBABA_ENDPOINT":                oss.Endpoint,
+	})
+}
+
+// getChartMuseumPrefix returns the prefix of charts in the bucket or container of registry
+func getChartMuseumPrefix(bucket, suffix string) string {
+	return fmt.Sprintf("%s-%s", bucket, suffix)
+}
+
+// getChartMuseumPrefixSuffix returns the suffix of chart museum prefix. The legacy suffix is kept
+// if the chart museum secret provisioned before uses it, so that the charts stored before are still found.
+func (m *MinIOReconciler) getChartMuseumPrefixSuffix() (string, error) {
+	var secret corev1.Secret
+	err := m.KubeClient.Get(types.NamespacedName{Namespace: m.HarborCluster.Namespace, Name: m.getChartMuseumSecretName()}, &secret)
+	if k8serror.IsNotFound(err) {
+		return ChartMuseumPrefixSuffix, nil
+	} else if err != nil {
+		return "", err
 	}
+
+	for key, value := range secret.Data {
+		if strings.HasSuffix(key, "_PREFIX") && strings.HasSuffix(string(value), "-"+LegacyChartMuseumPrefixSuffix) {
+			return LegacyChartMuseumPrefixSuffix, nil
+		}
+	}
+	return ChartMuseumPrefixSuffix, nil
 }
 
 func (m *MinIOReconciler) generateAzureSecret(labels map[string]string) (*corev1.Secret, error) {
@@ -282,8 +449,12 @@ func (m *MinIOReconciler) generateAzureSecret(labels map[string]string) (*corev1
 	}, nil
 }
 
+// generateGcsSecret returns the gcs storage secret of registry, the key is left empty with Workload Identity,
+// so that registry uses the application default credentials.
 func (m *MinIOReconciler) generateGcsSecret(labels map[string]string) (*corev1.Secret, error) {
-	dataJson, err := json.Marshal(m.HarborCluster.Spec.Storage.Gcs)
+	gcs := *m.HarborCluster.Spec.Storage.Gcs
+	gcs.ServiceAccount = ""
+	dataJson, err := json.Marshal(gcs)
 	if err != nil {
 		return nil, err
 	}
@@ -443,6 +614,9 @@ func (m *MinIOReconciler) generateIngress() *netv1.Ingress {
 
 	annotations := make(map[string]string)
 	annotations["nginx.ingress.kubernetes.io/proxy-body-size"] = "0"
+	if m.isMinIOTLSEnabled() {
+		annotations["nginx.ingress.kubernetes.io/backend-protocol"] = "HTTPS"
+	}
 
 	return &netv1.Ingress{
 		TypeMeta: metav1.TypeMeta{
@@ -501,21 +675,14 @@ func (m *MinIOReconciler) generateMinIOCR() *minio.Tenant {
 			},
 			ServiceName: m.getServiceName(),
 			Image:       "minio/minio:" + m.HarborCluster.Spec.Storage.InCluster.Spec.Version,
-			Zones: []minio.Zone{
-				{
-					Name:                DefaultZone,
-					Servers:             m.HarborCluster.Spec.Storage.InCluster.Spec.Replicas,
-					VolumesPerServer:    m.HarborCluster.Spec.Storage.InCluster.Spec.VolumesPerServer,
-					VolumeClaimTemplate: m.getVolumeClaimTemplate(),
-					Resources:           *m.getResourceRequirements(),
-				},
-			},
-			Mountpath: minio.MinIOVolumeMountPath,
+			Zones:       m.getZones(),
+			Mountpath:   minio.MinIOVolumeMountPath,
 			CredsSecret: &corev1.LocalObjectReference{
 				Name: m.getMinIOSecretNamespacedName().Name,
 			},
 			PodManagementPolicy: "Parallel",
 			RequestAutoCert:     false,
+			ExternalCertSecret:  m.getExternalCertSecret(),
 			CertConfig: &minio.CertificateConfig{
 				CommonName:       "",
 				OrganizationName: []string{},
@@ -1,7 +1,19 @@
 package storage
 
 import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
 	"github.com/goharbor/harbor-cluster-operator/lcm"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 func (m *MinIOReconciler) Update() (*lcm.CRStatus, error) {
@@ -24,11 +36,121 @@ func (m *MinIOReconciler) ExternalUpdate() (*lcm.CRStatus, error) {
 		return minioNotReadyStatus(UpdateExternalSecretError, err.Error()), err
 	}
 
-	p := &lcm.Property{
-		Name:  m.HarborCluster.Spec.Storage.Kind + ExternalStorageSecretSuffix,
-		Value: m.getExternalSecretName(),
+	if err := m.ReconcileChartMuseumSecret(); err != nil {
+		return minioNotReadyStatus(UpdateChartMuseumStorageSecretError, err.Error()), err
+	}
+
+	return m.externalStorageStatus(), nil
+}
+
+// externalStorageStatus probes the external storage, the storage is ready only if the probe passes,
+// otherwise the failing capability is reported as the reason. The passed probe is reused until the interval elapses
+// or the storage spec is changed, the failed probe is retried on every reconcile.
+func (m *MinIOReconciler) externalStorageStatus() *lcm.CRStatus {
+	// the claim of filesystem storage is only mounted by registry and chart museum
+	if m.HarborCluster.Spec.Storage.Kind == fileSystemStorage {
+		m.HarborCluster.Status.StorageProbe = nil
+		unmounted, err := m.getPodsWithoutFileSystem()
+		if err != nil {
+			return minioNotReadyStatus(FileSystemNotMountedError, err.Error())
+		}
+		if len(unmounted) > 0 {
+			return minioNotReadyStatus(FileSystemNotMountedError,
+				fmt.Sprintf("pods %s do not mount the filesystem storage, check the pod webhook of the operator", strings.Join(unmounted, ", ")))
+		}
+		return minioReadyStatus(m.getExternalProperties())
+	}
+
+	checksum, err := m.getStorageChecksum()
+	if err != nil {
+		return minioNotReadyStatus(StorageConnectError, err.Error())
+	}
+
+	probe := m.HarborCluster.Status.StorageProbe
+	if probe == nil || probe.Checksum != checksum || isProbeFailed(probe) || time.Since(probe.LastProbeTime.Time) >= StorageProbeInterval {
+		reason, err := m.ProbeExternalStorage()
+		probe = &goharborv1.StorageProbeStatus{
+			Checksum:      checksum,
+			Reason:        reason,
+			LastProbeTime: metav1.Now(),
+		}
+		if err != nil {
+			probe.Message = err.Error()
+		}
+		m.HarborCluster.Status.StorageProbe = probe
+	}
+
+	switch {
+	case probe.Reason == StorageUnverifiedReason:
+		status := minioReadyStatus(m.getExternalProperties())
+		status.Condition.Reason = probe.Reason
+		status.Condition.Message = fmt.Sprintf(MessageStorageUnverified, m.HarborCluster.Spec.Storage.Kind)
+		return status
+	case isProbeFailed(probe):
+		m.Log.Info("External storage probe failed.",
+			"namespace", m.HarborCluster.Namespace, "name", m.HarborCluster.Name, "reason", probe.Reason, "error", probe.Message)
+		return minioNotReadyStatus(probe.Reason, probe.Message)
+	}
+	return minioReadyStatus(m.getExternalProperties())
+}
+
+// isProbeFailed returns whether the storage failed the probe, the unverified storage is not probed at all
+func isProbeFailed(probe *goharborv1.StorageProbeStatus) bool {
+	return probe.Reason != "" && probe.Reason != StorageUnverifiedReason
+}
+
+// getStorageChecksum returns the checksum of storage spec, the storage secrets of registry and chart museum
+// are generated from it, so that the storage is probed again once the secrets are changed.
+func (m *MinIOReconciler) getStorageChecksum() (string, error) {
+	data, err := json.Marshal(m.HarborCluster.Spec.Storage)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// ReconcileChartMuseumSecret keeps the chart museum storage secret and the mounted gcs key in sync with the external storage,
+// it does nothing if chart museum is not enabled.
+func (m *MinIOReconciler) ReconcileChartMuseumSecret() error {
+	if err := m.ReconcileGcsKeySecret(); err != nil {
+		return err
+	}
+
+	desired, err := m.generateSecretForChartMuseum()
+	if err != nil || desired == nil {
+		return err
+	}
+	return m.applySecret(desired)
+}
+
+// applySecret creates the secret or updates its data, so that the secret follows the storage layout in spec.
+func (m *MinIOReconciler) applySecret(secret *corev1.Secret) error {
+	var current corev1.Secret
+	err := m.KubeClient.Get(types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}, &current)
+	if k8serror.IsNotFound(err) {
+		return m.KubeClient.Create(secret)
+	} else if err != nil {
+		return err
+	}
+
+	if cmp.Equal(secret.Data, current.Data) {
+		return nil
 	}
-	properties := &lcm.Properties{p}
 
-	return minioReadyStatus(properties), nil
+	m.Log.Info("Update storage secret.",
+		"namespace", current.Namespace, "name", current.Name)
+
+	current.Labels = secret.Labels
+	current.Data = secret.Data
+	return m.KubeClient.Update(&current)
+}
+
+// getExternalProperties returns the storage secrets of registry and chart museum
+func (m *MinIOReconciler) getExternalProperties() *lcm.Properties {
+	properties := &lcm.Properties{}
+	properties.Add(m.HarborCluster.Spec.Storage.Kind+ExternalStorageSecretSuffix, m.getExternalSecretName())
+	if m.HarborCluster.Spec.ChartMuseum != nil {
+		properties.Add(lcm.ChartMuseumSecretForStorage, m.getChartMuseumSecretName())
+	}
+	return properties
 }
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	"github.com/goharbor/harbor-cluster-operator/lcm"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	DefaultUsageWarningPercentage = 80
+	// StorageUsageInterval is the interval of collecting usage, minIO scans the usage in background anyway
+	StorageUsageInterval = 5 * time.Minute
+
+	DriveStateOK = "ok"
+)
+
+// UsageReconciler reports the usage and drive health of inCluster storage
+type UsageReconciler struct {
+	MinIOReconciler
+}
+
+// Reconcile collects the usage of buckets and the health of drives through minIO admin API periodically,
+// records them in status and metrics, and returns StorageUsageWarning condition.
+// It returns nil status if storage is not inCluster.
+func (u *UsageReconciler) Reconcile() (*lcm.CRStatus, error) {
+	if u.HarborCluster.Spec.Storage.Kind != inClusterStorage {
+		if u.HarborCluster.Status.StorageUsage != nil {
+			DeleteUsageMetrics(u.HarborCluster.Namespace, u.HarborCluster.Name)
+			u.HarborCluster.Status.StorageUsage = nil
+		}
+		return nil, nil
+	}
+
+	usage := u.HarborCluster.Status.StorageUsage
+	if usage == nil || time.Since(usage.LastUpdateTime.Time) >= StorageUsageInterval {
+		collected, err := u.collectUsage()
+		if err != nil {
+			return lcm.New(goharborv1.StorageUsageWarning).
+				WithStatus(corev1.ConditionUnknown).
+				WithReason(CollectStorageUsageError).
+				WithMessage(err.Error()), err
+		}
+		u.HarborCluster.Status.StorageUsage = collected
+		usage = collected
+	}
+
+	setUsageMetrics(u.HarborCluster, usage)
+	return u.usageStatus(usage), nil
+}
+
+func (u *UsageReconciler) collectUsage() (*goharborv1.StorageUsageStatus, error) {
+	client, err := u.newMinIOClient()
+	if err != nil {
+		return nil, err
+	}
+
+	dataUsage, err := client.GetDataUsage()
+	if err != nil {
+		return nil, err
+	}
+	drives, err := client.GetDrives()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &goharborv1.StorageUsageStatus{
+		CapacityBytes:  u.getProvisionedCapacity(),
+		LastUpdateTime: metav1.Now(),
+	}
+	for _, bucket := range u.getMinIOBuckets() {
+		bucketUsage := dataUsage.GetBucketUsage(bucket)
+		usage.Buckets = append(usage.Buckets, goharborv1.StorageBucketUsage{
+			Name:    bucket,
+			Size:    int64(bucketUsage.Size),
+			Objects: int64(bucketUsage.ObjectsCount),
+		})
+	}
+	for _, drive := range drives {
+		if drive.State == DriveStateOK {
+			usage.OnlineDrives++
+		} else {
+			usage.OfflineDrives++
+		}
+		usage.UsedBytes += int64(drive.UsedSpace)
+	}
+	if usage.CapacityBytes > 0 {
+		usage.UsedPercentage = int(usage.UsedBytes * 100 / usage.CapacityBytes)
+	}
+	return usage, nil
+}
+
+// getProvisionedCapacity returns the total size requested by the volumes of all zones
+func (u *UsageReconciler) getProvisionedCapacity() int64 {
+	var capacity int64
+	for _, zone := range u.getZones() {
+		if zone.VolumeClaimTemplate == nil {
+			continue
+		}
+		size := zone.VolumeClaimTemplate.Spec.Resources.Requests[corev1.ResourceStorage]
+		capacity += int64(zone.Servers) * int64(zone.VolumesPerServer) * size.Value()
+	}
+	return capacity
+}
+
+func (u *UsageReconciler) getUsageWarningPercentage() int {
+	if spec := u.HarborCluster.Spec.Storage.InCluster.Spec; spec != nil && spec.UsageWarningPercentage > 0 {
+		return spec.UsageWarningPercentage
+	}
+	return DefaultUsageWarningPercentage
+}
+
+// usageStatus returns the warning if any drive is offline, or the usage is above the warning percentage
+func (u *UsageReconciler) usageStatus(usage *goharborv1.StorageUsageStatus) *lcm.CRStatus {
+	status := lcm.New(goharborv1.StorageUsageWarning)
+	if usage.OfflineDrives > 0 {
+		return status.WithStatus(corev1.ConditionTrue).
+			WithReason(StorageDrivesOfflineReason).
+			WithMessage(fmt.Sprintf(MessageStorageDrivesOffline, usage.OfflineDrives, usage.OnlineDrives+usage.OfflineDrives))
+	}
+
+	if percentage := u.getUsageWarningPercentage(); usage.UsedPercentage >= percentage {
+		return status.WithStatus(corev1.ConditionTrue).
+			WithReason(StorageUsageHighReason).
+			WithMessage(fmt.Sprintf(MessageStorageUsageHigh, usage.UsedPercentage, percentage))
+	}
+	return status.WithStatus(corev1.ConditionFalse)
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"testing"
+
+	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestUsageStatus(t *testing.T) {
+	cases := []struct {
+		name       string
+		percentage int
+		usage      goharborv1.StorageUsageStatus
+		wantStatus corev1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name:       "healthy",
+			usage:      goharborv1.StorageUsageStatus{UsedPercentage: 50, OnlineDrives: 4},
+			wantStatus: corev1.ConditionFalse,
+		},
+		{
+			name:       "usage at the default warning percentage",
+			usage:      goharborv1.StorageUsageStatus{UsedPercentage: DefaultUsageWarningPercentage, OnlineDrives: 4},
+			wantStatus: corev1.ConditionTrue,
+			wantReason: StorageUsageHighReason,
+		},
+		{
+			name:       "usage below the custom warning percentage",
+			percentage: 90,
+			usage:      goharborv1.StorageUsageStatus{UsedPercentage: 85, OnlineDrives: 4},
+			wantStatus: corev1.ConditionFalse,
+		},
+		{
+			name:       "usage above the custom warning percentage",
+			percentage: 60,
+			usage:      goharborv1.StorageUsageStatus{UsedPercentage: 70, OnlineDrives: 4},
+			wantStatus: corev1.ConditionTrue,
+			wantReason: StorageUsageHighReason,
+		},
+		{
+			name:       "offline drives are reported before usage",
+			usage:      goharborv1.StorageUsageStatus{UsedPercentage: 95, OnlineDrives: 3, OfflineDrives: 1},
+			wantStatus: corev1.ConditionTrue,
+			wantReason: StorageDrivesOfflineReason,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			u := &UsageReconciler{MinIOReconciler{HarborCluster: &goharborv1.HarborCluster{Spec: goharborv1.HarborClusterSpec{
+				Storage: &goharborv1.Storage{
+					Kind:      inClusterStorage,
+					InCluster: &goharborv1.InCluster{Spec: &goharborv1.MinIOSpec{UsageWarningPercentage: c.percentage}},
+				},
+			}}}}
+			status := u.usageStatus(&c.usage)
+			if status.Condition.Type != goharborv1.StorageUsageWarning {
+				t.Errorf("usageStatus() type = %s, want %s", status.Condition.Type, goharborv1.StorageUsageWarning)
+			}
+			if status.Condition.Status != c.wantStatus || status.Condition.Reason != c.wantReason {
+				t.Errorf("usageStatus() = %s %q, want %s %q", status.Condition.Status, status.Condition.Reason, c.wantStatus, c.wantReason)
+			}
+		})
+	}
+}
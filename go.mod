@@ -3,19 +3,29 @@ module github.com/goharbor/harbor-cluster-operator
 go 1.13
 
 require (
+	cloud.google.com/go v0.57.0
+	cloud.google.com/go/storage v1.10.0
+	github.com/Azure/azure-storage-blob-go v0.10.0
 	github.com/blang/semver v3.5.0+incompatible
 	github.com/go-logr/logr v0.2.1-0.20200730175230-ee2de8da5be6
 	github.com/go-redis/redis v6.15.9+incompatible
 	github.com/goharbor/harbor-operator v0.5.2-0.20200817115335-b421dca2f798
+	github.com/golang/protobuf v1.4.2
 	github.com/google/go-cmp v0.5.1
+	github.com/jackc/pgconn v1.6.4
 	github.com/jackc/pgx/v4 v4.8.1
 	github.com/jetstack/cert-manager v0.16.1
 	github.com/minio/minio-go/v6 v6.0.57
+	github.com/ncw/swift v1.0.52
 	github.com/onsi/ginkgo v1.12.1
 	github.com/onsi/gomega v1.10.1
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.7.1
 	github.com/spotahome/redis-operator v1.0.0
 	github.com/zalando/postgres-operator v1.5.0
+	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
+	google.golang.org/api v0.28.0
+	google.golang.org/genproto v0.0.0-20200618031413-b414f8b61790
 	k8s.io/api v0.19.0-rc.3
 	k8s.io/apimachinery v0.19.0-rc.3
 	k8s.io/client-go v11.0.0+incompatible
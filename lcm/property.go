@@ -29,12 +29,13 @@ const (
 )
 
 const (
-	InClusterSecretForStorage string = "inClusterSecret"
-	AzureSecretForStorage     string = "azureSecret"
-	GcsSecretForStorage       string = "gcsSecret"
-	SwiftSecretForStorage     string = "swiftSecret"
-	S3SecretForStorage        string = "s3Secret"
-	OssSecretForStorage       string = "ossSecret"
+	InClusterSecretForStorage  string = "inClusterSecret"
+	AzureSecretForStorage      string = "azureSecret"
+	GcsSecretForStorage        string = "gcsSecret"
+	SwiftSecretForStorage      string = "swiftSecret"
+	S3SecretForStorage         string = "s3Secret"
+	OssSecretForStorage        string = "ossSecret"
+	FileSystemSecretForStorage string = "filesystemSecret"
 
 	ChartMuseumSecretForStorage string = "chartMuseumSecret"
 )
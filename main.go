@@ -25,6 +25,7 @@ import (
 
 	goharborv1 "github.com/goharbor/harbor-cluster-operator/apis/goharbor.io/v1alpha1"
 	"github.com/goharbor/harbor-cluster-operator/controllers"
+	"github.com/goharbor/harbor-cluster-operator/controllers/storage"
 	minio "github.com/goharbor/harbor-cluster-operator/controllers/storage/minio/api/v1"
 	redisCli "github.com/spotahome/redis-operator/api/redisfailover/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -32,6 +33,7 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -94,6 +96,9 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "HarborCluster")
 		os.Exit(1)
 	}
+	mgr.GetWebhookServer().Register("/mutate-v1-pod", &webhook.Admission{
+		Handler: &storage.StorageInjector{Client: mgr.GetClient()},
+	})
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")